@@ -7,13 +7,16 @@ import (
 	"time"
 
 	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/retry"
 
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
 )
 
 func TestRunPipelineExponentialBackoff(t *testing.T) {
 	var calls int
-	runSlingOnceFunc = func(ctx context.Context, bin, pipeline, state, jobID string, span trace.Span) (int, error) {
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
 		calls++
 		if calls < 4 {
 			return 0, fmt.Errorf("fail %d", calls)
@@ -23,15 +26,16 @@ func TestRunPipelineExponentialBackoff(t *testing.T) {
 	defer func() { runSlingOnceFunc = runSlingOnce }()
 
 	var sleeps []time.Duration
-	sleepFunc = func(d time.Duration) {
+	sleepFunc = func(ctx context.Context, d time.Duration) error {
 		sleeps = append(sleeps, d)
+		return nil
 	}
-	defer func() { sleepFunc = time.Sleep }()
+	defer func() { sleepFunc = sleepContext }()
 
 	tracer := trace.NewNoopTracerProvider().Tracer("test")
 	cfg := config.Config{MissionClusterID: "mc", StateLocation: "state", SyncMode: "normal", MaxRetries: 4, BackoffBase: time.Millisecond}
 
-	if err := runPipeline(testContext(), tracer, cfg, "pipe.yaml", "job1"); err != nil {
+	if err := (&runner{tracer: tracer}).runPipeline(testContext(), cfg, "pipe.yaml", "job1"); err != nil {
 		t.Fatalf("runPipeline returned error: %v", err)
 	}
 
@@ -45,3 +49,213 @@ func TestRunPipelineExponentialBackoff(t *testing.T) {
 		}
 	}
 }
+
+func TestRunPipelineStopsRetryingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(testContext())
+	defer cancel()
+
+	var calls int
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		calls++
+		return 0, fmt.Errorf("fail %d", calls)
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	sleepFunc = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+	defer func() { sleepFunc = sleepContext }()
+
+	tracer := trace.NewNoopTracerProvider().Tracer("test")
+	cfg := config.Config{MissionClusterID: "mc", StateLocation: "state", SyncMode: "normal", MaxRetries: 5, BackoffBase: time.Millisecond}
+
+	if err := (&runner{tracer: tracer}).runPipeline(ctx, cfg, "pipe.yaml", "job1"); err == nil {
+		t.Fatalf("expected error from runPipeline")
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 attempt after cancellation, got %d", calls)
+	}
+}
+
+func TestRunPipelineDoesNotRetryPermanentErrors(t *testing.T) {
+	var calls int
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		calls++
+		return 0, &slingError{ExitCode: 1, LogErrors: []string{"table public.userz does not exist"}, Err: fmt.Errorf("exit status 1")}
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	var sleeps int
+	sleepFunc = func(ctx context.Context, d time.Duration) error {
+		sleeps++
+		return nil
+	}
+	defer func() { sleepFunc = sleepContext }()
+
+	tracer := trace.NewNoopTracerProvider().Tracer("test")
+	cfg := config.Config{MissionClusterID: "mc", StateLocation: "state", SyncMode: "normal", MaxRetries: 5, BackoffBase: time.Millisecond}
+
+	if err := (&runner{tracer: tracer}).runPipeline(testContext(), cfg, "pipe.yaml", "job1"); err == nil {
+		t.Fatalf("expected error from runPipeline")
+	}
+	if calls != 1 || sleeps != 0 {
+		t.Fatalf("permanent error was retried: %d calls, %d sleeps", calls, sleeps)
+	}
+}
+
+func TestRunPipelineRetryBudget(t *testing.T) {
+	var calls int
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		calls++
+		return 0, fmt.Errorf("connection reset")
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	var sleeps []time.Duration
+	sleepFunc = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	defer func() { sleepFunc = sleepContext }()
+
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+	cfg := config.Config{MissionClusterID: "mc", StateLocation: "state", SyncMode: "normal", MaxRetries: 10, BackoffBase: time.Second, RetryBudget: 2500 * time.Millisecond}
+
+	if err := (&runner{tracer: tracer}).runPipeline(testContext(), cfg, "pipe.yaml", "job1"); err == nil {
+		t.Fatalf("expected error from runPipeline")
+	}
+	if calls != 2 || len(sleeps) != 1 || sleeps[0] != time.Second {
+		t.Fatalf("expected budget to stop after 2 attempts, got %d calls and sleeps %v", calls, sleeps)
+	}
+
+	var backoffs, exhausted int
+	for _, e := range pipelineSpans(sr)[0].Events() {
+		switch e.Name {
+		case "retry backoff":
+			backoffs++
+		case "retry budget exhausted":
+			exhausted++
+		}
+	}
+	if backoffs != 1 || exhausted != 1 {
+		t.Fatalf("expected 1 backoff and 1 exhausted event, got %d and %d", backoffs, exhausted)
+	}
+}
+
+func TestRunPipelineAttemptSpans(t *testing.T) {
+	var calls int
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		calls++
+		span.AddEvent(fmt.Sprintf("sling log %d", calls))
+		if calls == 1 {
+			return 2, fmt.Errorf("execute sling: %w", &slingError{ExitCode: 1, Err: fmt.Errorf("connection reset")})
+		}
+		return 5, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+	sleepFunc = func(ctx context.Context, d time.Duration) error { return nil }
+	defer func() { sleepFunc = sleepContext }()
+
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+	cfg := config.Config{MissionClusterID: "mc", StateLocation: "state", SyncMode: "normal", MaxRetries: 3, BackoffBase: time.Second}
+	if err := (&runner{tracer: tracer}).runPipeline(testContext(), cfg, "pipe.yaml", "job1"); err != nil {
+		t.Fatalf("runPipeline: %v", err)
+	}
+
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, s := range sr.Ended() {
+		spans[s.Name()] = append(spans[s.Name()], s)
+	}
+	if len(spans["sling.sync.run"]) != 1 || len(spans["sling.sync.attempt"]) != 2 || len(spans["backoff"]) != 1 {
+		t.Fatalf("spans = %v, want one run, two attempts and one backoff", spans)
+	}
+	parent := spans["sling.sync.run"][0]
+	attrs := func(s sdktrace.ReadOnlySpan) map[string]string {
+		out := map[string]string{}
+		for _, kv := range s.Attributes() {
+			out[string(kv.Key)] = kv.Value.Emit()
+		}
+		return out
+	}
+	want := []map[string]string{
+		{"attempt": "1", "exit_code": "1", "rows_synced": "2", "error.class": "transient", "status": "failed"},
+		{"attempt": "2", "exit_code": "0", "rows_synced": "5", "status": "success"},
+	}
+	for i, s := range spans["sling.sync.attempt"] {
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("attempt %d is not a child of the run span", i+1)
+		}
+		got := attrs(s)
+		for k, v := range want[i] {
+			if got[k] != v {
+				t.Errorf("attempt %d %s = %q, want %q", i+1, k, got[k], v)
+			}
+		}
+		if events := s.Events(); len(events) == 0 || events[0].Name != fmt.Sprintf("sling log %d", i+1) {
+			t.Errorf("attempt %d events = %v, want its own Sling log", i+1, events)
+		}
+	}
+	backoff := spans["backoff"][0]
+	if backoff.Parent().SpanID() != parent.SpanContext().SpanID() || attrs(backoff)["wait_seconds"] != "1" {
+		t.Errorf("backoff span = %v under %s, want a 1s wait under the run span", attrs(backoff), backoff.Parent().SpanID())
+	}
+	// The run span keeps the totals.
+	if got := attrs(parent); got["rows_synced"] != "7" || got["status"] != "success" {
+		t.Errorf("run span attributes = %v", got)
+	}
+}
+
+func TestRunPipelineSharedRunBudget(t *testing.T) {
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		return 0, fmt.Errorf("connection reset")
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	var sleeps int
+	sleepFunc = func(ctx context.Context, d time.Duration) error {
+		sleeps++
+		return nil
+	}
+	defer func() { sleepFunc = sleepContext }()
+
+	tracer := trace.NewNoopTracerProvider().Tracer("test")
+	cfg := config.Config{MissionClusterID: "mc", StateLocation: "state", SyncMode: "normal", MaxRetries: 3, BackoffBase: time.Second}
+	r := &runner{tracer: tracer, retryBudget: retry.NewBudget(3500 * time.Millisecond)}
+
+	r.runPipeline(testContext(), cfg, "a.yaml", "job1") // waits 1s and 2s
+	r.runPipeline(testContext(), cfg, "b.yaml", "job2") // budget already spent
+	if sleeps != 2 {
+		t.Fatalf("expected the run budget to allow 2 waits in total, got %d", sleeps)
+	}
+}
+
+func TestRunPipelineBackoffCap(t *testing.T) {
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		return 0, fmt.Errorf("connection reset")
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	var sleeps []time.Duration
+	sleepFunc = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	defer func() { sleepFunc = sleepContext }()
+
+	tracer := trace.NewNoopTracerProvider().Tracer("test")
+	cfg := config.Config{MissionClusterID: "mc", StateLocation: "state", SyncMode: "normal", MaxRetries: 5, BackoffBase: time.Millisecond, BackoffMax: 3 * time.Millisecond}
+	(&runner{tracer: tracer}).runPipeline(testContext(), cfg, "pipe.yaml", "job1")
+
+	expected := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 3 * time.Millisecond}
+	if len(sleeps) != len(expected) {
+		t.Fatalf("expected %d sleeps, got %v", len(expected), sleeps)
+	}
+	for i, d := range expected {
+		if sleeps[i] != d {
+			t.Fatalf("sleep %d = %v, want %v", i, sleeps[i], d)
+		}
+	}
+}
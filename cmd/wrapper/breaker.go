@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"sling-sync-wrapper/internal/breaker"
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/logging"
+)
+
+// healthStoreFile is the name of the circuit breaker store in the data dir.
+const healthStoreFile = "sling_health.json"
+
+// circuitOpenError is returned for pipelines skipped because their circuit
+// breaker is open.
+type circuitOpenError struct {
+	pipeline string
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for pipeline %q", e.pipeline)
+}
+
+func healthStorePath(cfg config.Config) string {
+	return filepath.Join(config.DataDir(cfg), healthStoreFile)
+}
+
+// openHealthStore opens the pipeline health store for normal runs that use
+// circuit breaking or a run deadline. Problems with the store are logged
+// rather than returned so that a broken store never stops data from syncing.
+func openHealthStore(ctx context.Context, cfg config.Config) *breaker.Store {
+	if cfg.SyncMode != "normal" || (cfg.BreakerThreshold <= 0 && cfg.RunDeadline <= 0) {
+		return nil
+	}
+	store, err := breaker.Open(healthStorePath(cfg), cfg.BreakerThreshold, cfg.BreakerCooldown)
+	if err != nil {
+		logging.FromContext(ctx).Warn("health store unavailable, circuit breaker disabled", "err", err)
+		return nil
+	}
+	return store
+}
+
+// recordHealth updates the health store with the outcome of a run of
+// pipeline that took duration. Interrupted runs and runs that lost their
+// lock say nothing about the pipeline's health.
+func (r *runner) recordHealth(ctx context.Context, pipeline string, runErr error, duration time.Duration) {
+	if r.health == nil {
+		return
+	}
+	switch statusFromErr(runErr) {
+	case "cancelled", "deadline_exceeded", "lock_lost":
+		return
+	}
+	logger := logging.FromContext(ctx)
+	now := time.Now()
+	if runErr == nil {
+		if err := r.health.RecordSuccess(pipeline, now, duration); err != nil {
+			logger.WarnContext(ctx, "failed to update health store", "err", err)
+		}
+		return
+	}
+	state, err := r.health.RecordFailure(pipeline, now)
+	if err != nil {
+		logger.WarnContext(ctx, "failed to update health store", "err", err)
+	}
+	if state == breaker.StateOpen {
+		logger.WarnContext(ctx, "circuit opened", "circuit_state", state)
+	}
+}
+
+func newBreakerCmd(cfg *config.Config, logger *slog.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "breaker",
+		Short: "Inspect and reset per-pipeline circuit breakers",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Show the circuit breaker state of each pipeline",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := breaker.Open(healthStorePath(*cfg), cfg.BreakerThreshold, cfg.BreakerCooldown)
+			if err != nil {
+				return err
+			}
+			records := store.Records()
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PIPELINE\tSTATE\tFAILURES\tOPENED AT\tLAST SUCCESS")
+			for _, name := range store.Names() {
+				r := records[name]
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", name, r.State, r.ConsecutiveFailures, formatTime(r.OpenedAt), formatTime(r.LastSuccess))
+			}
+			return w.Flush()
+		},
+	})
+
+	var all bool
+	reset := &cobra.Command{
+		Use:   "reset [pipeline...]",
+		Short: "Close the circuit breakers of the given pipelines",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !all {
+				return fmt.Errorf("name the pipelines to reset or pass --all")
+			}
+			if len(args) > 0 && all {
+				return fmt.Errorf("--all cannot be combined with pipeline names")
+			}
+			store, err := breaker.Open(healthStorePath(*cfg), cfg.BreakerThreshold, cfg.BreakerCooldown)
+			if err != nil {
+				return err
+			}
+			if err := store.Reset(args...); err != nil {
+				return err
+			}
+			logger.Info("circuit breakers reset", "pipelines", args, "all", all)
+			return nil
+		},
+	}
+	reset.Flags().BoolVar(&all, "all", false, "Reset the circuit breakers of all pipelines")
+	cmd.AddCommand(reset)
+
+	return cmd
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
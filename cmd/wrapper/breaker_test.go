@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/breaker"
+	"sling-sync-wrapper/internal/config"
+)
+
+func TestRunOpensCircuit(t *testing.T) {
+	sr := recordSpans(t)
+
+	dir := writePipelines(t, "a.yaml")
+	calls := 0
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		calls++
+		return 0, fmt.Errorf("boom")
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", SyncMode: "normal",
+		MaxRetries: 1, Concurrency: 1, DataDir: t.TempDir(), BreakerThreshold: 2, BreakerCooldown: time.Hour}
+	for i := 0; i < 3; i++ {
+		if err := run(testContext(), cfg); err == nil {
+			t.Fatalf("run %d: expected error", i+1)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("sling called %d times, want 2", calls)
+	}
+
+	spans := pipelineSpans(sr)
+	last := spans[len(spans)-1]
+	var status, state string
+	for _, kv := range last.Attributes() {
+		switch kv.Key {
+		case "status":
+			status = kv.Value.AsString()
+		case "circuit.state":
+			state = kv.Value.AsString()
+		}
+	}
+	if status != "circuit_open" || state != string(breaker.StateOpen) {
+		t.Fatalf("last span status=%q circuit.state=%q, want circuit_open/open", status, state)
+	}
+
+	err := run(testContext(), cfg)
+	var open *circuitOpenError
+	if !errors.As(err, &open) {
+		t.Fatalf("expected circuitOpenError, got %v", err)
+	}
+}
+
+func TestBreakerResetCmd(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := breaker.Open(filepath.Join(dataDir, healthStoreFile), 1, time.Hour)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	store.RecordFailure("a", time.Now())
+	store.RecordFailure("b", time.Now())
+
+	t.Setenv("SYNC_DATA_DIR", dataDir)
+	t.Setenv("SYNC_BREAKER_THRESHOLD", "1")
+
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"breaker", "reset", "a"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd = newRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"breaker", "list"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if strings.Contains(out.String(), "a ") || !strings.Contains(out.String(), "b ") {
+		t.Fatalf("unexpected list output:\n%s", out.String())
+	}
+
+	cmd = newRootCmd()
+	cmd.SetArgs([]string{"breaker", "reset"})
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected error without pipelines or --all")
+	}
+}
+
+func TestSubcommandsHonorFlags(t *testing.T) {
+	stubTracing(t)
+	dir := writePipelines(t, "a.yaml")
+	t.Setenv("SLING_CONFIG", "")
+	t.Setenv("PIPELINE_DIR", "")
+
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"noop", "--config", filepath.Join(dir, "a.yaml")})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("noop with --config: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"sling-sync-wrapper/internal/config"
+)
+
+// errorClass tells the retry loop whether a failure is worth retrying.
+type errorClass string
+
+const (
+	// classTransient failures (network, locks, timeouts) may succeed when
+	// retried. Failures that match no rule are treated as transient.
+	classTransient errorClass = "transient"
+	// classPermanent failures (auth, missing tables, bad configuration) fail
+	// the same way on every attempt.
+	classPermanent errorClass = "permanent"
+)
+
+type classifyRule struct {
+	pattern *regexp.Regexp
+	class   errorClass
+}
+
+// defaultRules are consulted after any configured rules. Permanent patterns
+// come first so that e.g. an authentication failure reported together with a
+// closed connection is not retried.
+var defaultRules = []classifyRule{
+	{regexp.MustCompile(`(?i)authentication failed|access denied|permission denied|invalid password|unauthori[sz]ed`), classPermanent},
+	{regexp.MustCompile(`(?i)does not exist|no such table|unknown (table|column|database)|table .* not found`), classPermanent},
+	{regexp.MustCompile(`(?i)yaml:|could not parse|unmarshal|invalid config|syntax error`), classPermanent},
+	{regexp.MustCompile(`(?i)connection (refused|reset)|broken pipe|i/o timeout|timed out|timeout|no route to host|no such host|unexpected EOF`), classTransient},
+	{regexp.MustCompile(`(?i)deadlock|lock wait|could not obtain lock|database is locked|too many connections|temporarily unavailable|service unavailable`), classTransient},
+}
+
+// errorClassifier classifies Sling failures using configured rules followed by
+// the built-in defaults. A nil *errorClassifier uses only the defaults.
+type errorClassifier struct {
+	rules []classifyRule
+}
+
+// newErrorClassifier compiles the configured rules.
+func newErrorClassifier(rules []config.ErrorRule) (*errorClassifier, error) {
+	c := &errorClassifier{}
+	for _, r := range rules {
+		class := errorClass(r.Class)
+		if class != classTransient && class != classPermanent {
+			return nil, fmt.Errorf("error rule %q: unknown class %q", r.Pattern, r.Class)
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("error rule %q: %w", r.Pattern, err)
+		}
+		c.rules = append(c.rules, classifyRule{pattern: re, class: class})
+	}
+	return c, nil
+}
+
+// classify inspects context errors, the Sling exit code, the errors Sling
+// logged and its stderr output to decide whether err is transient.
+func (c *errorClassifier) classify(err error) errorClass {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return classTransient
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return classPermanent
+	}
+
+	text := []string{err.Error()}
+	var slingErr *slingError
+	if errors.As(err, &slingErr) {
+		switch slingErr.ExitCode {
+		case 126, 127: // not executable, command not found
+			return classPermanent
+		}
+		text = append(text, slingErr.LogErrors...)
+		text = append(text, slingErr.Stderr)
+	}
+	joined := strings.Join(text, "\n")
+
+	if c != nil {
+		if class, ok := matchRules(c.rules, joined); ok {
+			return class
+		}
+	}
+	if class, ok := matchRules(defaultRules, joined); ok {
+		return class
+	}
+	return classTransient
+}
+
+func matchRules(rules []classifyRule, text string) (errorClass, bool) {
+	for _, r := range rules {
+		if r.pattern.MatchString(text) {
+			return r.class, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"sling-sync-wrapper/internal/config"
+)
+
+func TestClassifyDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want errorClass
+	}{
+		{"deadline", fmt.Errorf("command context: %w", context.DeadlineExceeded), classTransient},
+		{"binary missing", fmt.Errorf("start sling: %w", exec.ErrNotFound), classPermanent},
+		{"exit 127", &slingError{ExitCode: 127, Err: fmt.Errorf("exit status 127")}, classPermanent},
+		{"missing table in log", &slingError{ExitCode: 1, LogErrors: []string{`relation "public.userz" does not exist`}, Err: fmt.Errorf("exit status 1")}, classPermanent},
+		{"auth in stderr", &slingError{ExitCode: 1, Stderr: "pq: password authentication failed for user sling", Err: fmt.Errorf("exit status 1")}, classPermanent},
+		{"connection reset", &slingError{ExitCode: 1, LogErrors: []string{"read tcp: connection reset by peer"}, Err: fmt.Errorf("exit status 1")}, classTransient},
+		{"database locked", &slingError{ExitCode: 1, Stderr: "database is locked", Err: fmt.Errorf("exit status 1")}, classTransient},
+		{"unmatched", fmt.Errorf("something odd happened"), classTransient},
+	}
+
+	var c *errorClassifier
+	for _, tt := range tests {
+		if got := c.classify(tt.err); got != tt.want {
+			t.Errorf("%s: classify = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyCustomRulesFirst(t *testing.T) {
+	c, err := newErrorClassifier([]config.ErrorRule{
+		{Pattern: `ORA-12541`, Class: "transient"},
+		{Pattern: `(?i)connection reset`, Class: "permanent"},
+	})
+	if err != nil {
+		t.Fatalf("newErrorClassifier: %v", err)
+	}
+
+	if got := c.classify(&slingError{ExitCode: 1, Stderr: "ORA-12541: TNS:no listener", Err: fmt.Errorf("exit status 1")}); got != classTransient {
+		t.Errorf("ORA-12541 classified as %s", got)
+	}
+	if got := c.classify(&slingError{ExitCode: 1, Stderr: "connection reset by peer", Err: fmt.Errorf("exit status 1")}); got != classPermanent {
+		t.Errorf("custom rule did not override default, got %s", got)
+	}
+}
+
+func TestNewErrorClassifierInvalid(t *testing.T) {
+	if _, err := newErrorClassifier([]config.ErrorRule{{Pattern: "x", Class: "sometimes"}}); err == nil {
+		t.Errorf("expected error for unknown class")
+	}
+	if _, err := newErrorClassifier([]config.ErrorRule{{Pattern: "(", Class: "transient"}}); err == nil {
+		t.Errorf("expected error for invalid pattern")
+	}
+}
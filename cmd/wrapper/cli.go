@@ -3,6 +3,9 @@ package main
 import (
 	"context"
 	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 
@@ -24,59 +27,126 @@ telemetry, retry logic, and state management. Configuration can be
 supplied via flags or environment variables.`,
 		SilenceUsage: true,
 	}
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &configError{err}
+	})
 
 	cmd.PersistentFlags().StringVar(&cfg.MissionClusterID, "mission-cluster-id", cfg.MissionClusterID, "Source mission cluster identifier (env: MISSION_CLUSTER_ID)")
 	cmd.PersistentFlags().StringVar(&cfg.PipelineFile, "config", cfg.PipelineFile, "Path to a single pipeline YAML file (env: SLING_CONFIG)")
 	cmd.PersistentFlags().StringVar(&cfg.PipelineDir, "pipeline-dir", cfg.PipelineDir, "Directory containing pipeline YAML files (env: PIPELINE_DIR)")
 	cmd.PersistentFlags().StringVar(&cfg.StateLocation, "state", cfg.StateLocation, "URI where sync state is stored (env: SLING_STATE)")
-	cmd.PersistentFlags().StringVar(&cfg.OTELEndpoint, "otel-endpoint", cfg.OTELEndpoint, "OpenTelemetry collector endpoint (env: OTEL_EXPORTER_OTLP_ENDPOINT)")
+	cmd.PersistentFlags().StringVar(&cfg.OTELEndpoint, "otel-endpoint", cfg.OTELEndpoint, "OpenTelemetry collector endpoint, host:port or an http(s) URL; localhost:4317 for grpc and localhost:4318 for http/protobuf if unset (env: OTEL_EXPORTER_OTLP_ENDPOINT)")
+	cmd.PersistentFlags().StringVar(&cfg.OTELProtocol, "otel-protocol", cfg.OTELProtocol, "OTLP protocol: grpc or http/protobuf (env: OTEL_EXPORTER_OTLP_PROTOCOL)")
+	cmd.PersistentFlags().StringVar(&cfg.OTELHeaders, "otel-headers", cfg.OTELHeaders, "Headers sent with every OTLP export as key=value pairs separated by commas (env: OTEL_EXPORTER_OTLP_HEADERS)")
+	cmd.PersistentFlags().StringVar(&cfg.OTELCertificate, "otel-certificate", cfg.OTELCertificate, "PEM file of CAs to verify the collector with; turns on TLS (env: OTEL_EXPORTER_OTLP_CERTIFICATE)")
+	cmd.PersistentFlags().StringVar(&cfg.OTELClientCertificate, "otel-client-certificate", cfg.OTELClientCertificate, "PEM client certificate for mTLS to the collector (env: OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE)")
+	cmd.PersistentFlags().StringVar(&cfg.OTELClientKey, "otel-client-key", cfg.OTELClientKey, "PEM key of --otel-client-certificate (env: OTEL_EXPORTER_OTLP_CLIENT_KEY)")
+	cmd.PersistentFlags().StringVar(&cfg.OTELCompression, "otel-compression", cfg.OTELCompression, "OTLP compression: gzip or none (env: OTEL_EXPORTER_OTLP_COMPRESSION)")
+	cmd.PersistentFlags().DurationVar(&cfg.OTELTimeout, "otel-timeout", cfg.OTELTimeout, "Timeout of each OTLP export (env: OTEL_EXPORTER_OTLP_TIMEOUT, in milliseconds)")
+	cmd.PersistentFlags().StringVar(&cfg.TracesSampler, "traces-sampler", cfg.TracesSampler, "Trace sampler, e.g. parentbased_always_on or traceidratio (env: OTEL_TRACES_SAMPLER)")
+	cmd.PersistentFlags().StringVar(&cfg.TracesSamplerArg, "traces-sampler-arg", cfg.TracesSamplerArg, "Ratio of the traceidratio samplers (env: OTEL_TRACES_SAMPLER_ARG)")
+	cmd.PersistentFlags().StringVar(&cfg.TelemetryExporter, "telemetry-exporter", cfg.TelemetryExporter, "Where telemetry goes: otlp, stdout, or file for --telemetry-file (env: SYNC_TELEMETRY_EXPORTER)")
+	cmd.PersistentFlags().StringVar(&cfg.TelemetryFile, "telemetry-file", cfg.TelemetryFile, "File the file exporter appends telemetry to as JSON lines (env: SYNC_TELEMETRY_FILE)")
+	cmd.PersistentFlags().StringVar(&cfg.TraceParent, "traceparent", cfg.TraceParent, "W3C traceparent of a parent trace to continue (env: TRACEPARENT)")
+	cmd.PersistentFlags().StringVar(&cfg.TraceState, "tracestate", cfg.TraceState, "W3C tracestate accompanying --traceparent (env: TRACESTATE)")
+	cmd.PersistentFlags().BoolVar(&cfg.RequireTelemetry, "require-telemetry", cfg.RequireTelemetry, "Fail instead of running without telemetry when it cannot be set up (env: SYNC_REQUIRE_TELEMETRY)")
+	cmd.PersistentFlags().IntVar(&cfg.SpanSpoolMaxBytes, "span-spool-max-bytes", cfg.SpanSpoolMaxBytes, "Size cap of the spool of undelivered spans in the data dir, 0 to disable (env: SYNC_SPAN_SPOOL_MAX_BYTES)")
 	cmd.PersistentFlags().IntVar(&cfg.MaxRetries, "max-retries", cfg.MaxRetries, "Maximum retry attempts for failed syncs (env: SYNC_MAX_RETRIES)")
 	cmd.PersistentFlags().DurationVar(&cfg.BackoffBase, "backoff-base", cfg.BackoffBase, "Base duration for exponential backoff (env: SYNC_BACKOFF_BASE)")
+	cmd.PersistentFlags().DurationVar(&cfg.BackoffMax, "backoff-max", cfg.BackoffMax, "Maximum duration of a single backoff wait, 0 for no cap (env: SYNC_BACKOFF_MAX)")
+	cmd.PersistentFlags().StringVar(&cfg.BackoffJitter, "backoff-jitter", cfg.BackoffJitter, "Backoff jitter mode: none, full, equal or decorrelated (env: SYNC_BACKOFF_JITTER)")
+	cmd.PersistentFlags().DurationVar(&cfg.RetryBudget, "retry-budget", cfg.RetryBudget, "Maximum time a pipeline may spend retrying, 0 for unlimited (env: SYNC_RETRY_BUDGET)")
+	cmd.PersistentFlags().DurationVar(&cfg.RunRetryBudget, "run-retry-budget", cfg.RunRetryBudget, "Maximum time all pipelines of a run may spend retrying, 0 for unlimited (env: SYNC_RUN_RETRY_BUDGET)")
+	cmd.PersistentFlags().StringVar(&cfg.DataDir, "data-dir", cfg.DataDir, "Directory for files the wrapper keeps between runs, defaults to the directory of a file state (env: SYNC_DATA_DIR)")
+	cmd.PersistentFlags().IntVar(&cfg.BreakerThreshold, "breaker-threshold", cfg.BreakerThreshold, "Consecutive failed runs after which a pipeline's circuit opens, 0 to disable (env: SYNC_BREAKER_THRESHOLD)")
+	cmd.PersistentFlags().DurationVar(&cfg.BreakerCooldown, "breaker-cooldown", cfg.BreakerCooldown, "Time an open circuit skips its pipeline before a trial run (env: SYNC_BREAKER_COOLDOWN)")
 	cmd.PersistentFlags().StringVar(&cfg.SlingBinary, "sling-binary", cfg.SlingBinary, "Path to the Sling CLI binary (env: SLING_BIN)")
 	cmd.PersistentFlags().DurationVar(&cfg.SlingTimeout, "sling-timeout", cfg.SlingTimeout, "Maximum duration for a single Sling run (env: SLING_TIMEOUT)")
+	cmd.PersistentFlags().IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "Number of pipelines to run in parallel (env: SYNC_CONCURRENCY)")
+	cmd.PersistentFlags().DurationVar(&cfg.ShutdownGrace, "shutdown-grace", cfg.ShutdownGrace, "Time Sling is given to exit after SIGTERM before it is killed (env: SYNC_SHUTDOWN_GRACE)")
+	cmd.PersistentFlags().DurationVar(&cfg.RunDeadline, "run-deadline", cfg.RunDeadline, "Maximum duration of a whole run; pipelines that no longer fit are deferred, 0 for no deadline (env: SYNC_RUN_DEADLINE)")
+	cmd.PersistentFlags().StringVar(&cfg.ReportFile, "report", cfg.ReportFile, "Write a run report to this file (env: SYNC_REPORT)")
+	cmd.PersistentFlags().StringVar(&cfg.ReportFormat, "report-format", cfg.ReportFormat, "Run report format: json or junit (env: SYNC_REPORT_FORMAT)")
+	cmd.PersistentFlags().StringVar(&cfg.TerminationLog, "termination-log", cfg.TerminationLog, "Write a run summary to this file, e.g. /dev/termination-log (env: SYNC_TERMINATION_LOG)")
+	cmd.PersistentFlags().StringVar(&cfg.PushgatewayURL, "pushgateway-url", cfg.PushgatewayURL, "Push Prometheus metrics to this Pushgateway at the end of a run (env: SYNC_PUSHGATEWAY_URL)")
+	cmd.PersistentFlags().StringVar(&cfg.LockBackend, "lock-backend", cfg.LockBackend, "Run lock backend: file, sql or none (env: SYNC_LOCK_BACKEND)")
+	cmd.PersistentFlags().StringVar(&cfg.LockDSN, "lock-dsn", cfg.LockDSN, "Database for the sql lock backend, a postgres:// or sqlite3:// URL (env: SYNC_LOCK_DSN)")
+	cmd.PersistentFlags().DurationVar(&cfg.LockTTL, "lock-ttl", cfg.LockTTL, "Time after which a sql lock lease that is not renewed expires (env: SYNC_LOCK_TTL)")
+	cmd.PersistentFlags().DurationVar(&cfg.WaitForLock, "wait-for-lock", cfg.WaitForLock, "Time to wait for a held pipeline lock before giving up, 0 to give up at once (env: SYNC_WAIT_FOR_LOCK)")
+	cmd.PersistentFlags().StringVar(&cfg.ErrorRulesFile, "error-rules", cfg.ErrorRulesFile, "YAML file with regex rules classifying Sling errors as transient or permanent (env: SYNC_ERROR_RULES)")
 
-	cmd.AddCommand(newRunCmd(cfg, logger), newBackfillCmd(cfg, logger), newNoopCmd(cfg, logger))
+	// Subcommands receive a pointer so that they see the parsed flag values.
+	cmd.AddCommand(newRunCmd(&cfg, logger), newBackfillCmd(&cfg, logger), newNoopCmd(&cfg, logger), newBreakerCmd(&cfg, logger), newDaemonCmd(&cfg, logger), newTelemetryCmd(&cfg, logger))
 
 	return cmd
 }
 
-func newRunCmd(cfg config.Config, logger *slog.Logger) *cobra.Command {
-	return &cobra.Command{
+func newRunCmd(cfg *config.Config, logger *slog.Logger) *cobra.Command {
+	var resume bool
+	cmd := &cobra.Command{
 		Use:   "run",
 		Short: "Run configured pipelines",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := *cfg
 			cfg.SyncMode = "normal"
-			ctx := logging.NewContext(context.Background(), logger)
+			cfg.Resume = resume
+			ctx := logging.NewContext(cmd.Context(), logger)
 			return run(ctx, cfg)
 		},
 	}
+	cmd.Flags().BoolVar(&resume, "resume", false, "Only run the pipelines that failed, were cancelled or never started in the previous run")
+	return cmd
+}
+
+func newDaemonCmd(cfg *config.Config, logger *slog.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Run pipelines on a schedule until interrupted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := *cfg
+			cfg.SyncMode = "normal"
+			ctx := logging.NewContext(cmd.Context(), logger)
+			return runDaemon(ctx, cfg)
+		},
+	}
+	cmd.Flags().StringVar(&cfg.Schedule, "schedule", cfg.Schedule, "Cron expression or interval such as 10m for pipelines without their own schedule (env: SYNC_SCHEDULE)")
+	cmd.Flags().DurationVar(&cfg.ScheduleJitter, "schedule-jitter", cfg.ScheduleJitter, "Maximum random delay added to each scheduled run (env: SYNC_SCHEDULE_JITTER)")
+	cmd.Flags().StringVar(&cfg.HTTPAddr, "http-addr", cfg.HTTPAddr, "Serve health, status and run triggers on this address, e.g. :8080 (env: SYNC_HTTP_ADDR)")
+	cmd.Flags().StringVar(&cfg.CatchUp, "catch-up", cfg.CatchUp, "What to do about missed runs: skip, or once to run once right away (env: SYNC_CATCHUP)")
+	return cmd
 }
 
-func newBackfillCmd(cfg config.Config, logger *slog.Logger) *cobra.Command {
+func newBackfillCmd(cfg *config.Config, logger *slog.Logger) *cobra.Command {
 	return &cobra.Command{
 		Use:   "backfill",
 		Short: "Reset sync state and exit",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := *cfg
 			cfg.SyncMode = "backfill"
-			ctx := logging.NewContext(context.Background(), logger)
+			ctx := logging.NewContext(cmd.Context(), logger)
 			return run(ctx, cfg)
 		},
 	}
 }
 
-func newNoopCmd(cfg config.Config, logger *slog.Logger) *cobra.Command {
+func newNoopCmd(cfg *config.Config, logger *slog.Logger) *cobra.Command {
 	return &cobra.Command{
 		Use:   "noop",
 		Short: "Validate configuration without running pipelines",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := *cfg
 			cfg.SyncMode = "noop"
-			ctx := logging.NewContext(context.Background(), logger)
+			ctx := logging.NewContext(cmd.Context(), logger)
 			return run(ctx, cfg)
 		},
 	}
 }
 
-// Execute runs the CLI.
+// Execute runs the CLI. SIGINT and SIGTERM cancel the command context so that
+// running pipelines can shut down gracefully.
 func Execute() error {
-	return newRootCmd().Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return newRootCmd().ExecuteContext(ctx)
 }
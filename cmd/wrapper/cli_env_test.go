@@ -10,10 +10,44 @@ func TestNewRootCmdEnv(t *testing.T) {
 	t.Setenv("SLING_CONFIG", "env-pipeline.yaml")
 	t.Setenv("SLING_STATE", "env-state.json")
 	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel-env:4317")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/protobuf")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=env")
+	t.Setenv("OTEL_EXPORTER_OTLP_CERTIFICATE", "/env/ca.pem")
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE", "/env/client.pem")
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_KEY", "/env/client-key.pem")
+	t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "gzip")
+	t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "3000")
+	t.Setenv("OTEL_TRACES_SAMPLER", "parentbased_traceidratio")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.1")
+	t.Setenv("SYNC_TELEMETRY_EXPORTER", "stdout")
+	t.Setenv("SYNC_TELEMETRY_FILE", "/env/telemetry.jsonl")
 	t.Setenv("SYNC_MAX_RETRIES", "7")
 	t.Setenv("SYNC_BACKOFF_BASE", "3s")
 	t.Setenv("SLING_BIN", "/env/sling")
 	t.Setenv("SLING_TIMEOUT", "45s")
+	t.Setenv("SYNC_CONCURRENCY", "3")
+	t.Setenv("SYNC_SHUTDOWN_GRACE", "15s")
+	t.Setenv("SYNC_ERROR_RULES", "/env/rules.yaml")
+	t.Setenv("SYNC_BACKOFF_MAX", "2m0s")
+	t.Setenv("SYNC_BACKOFF_JITTER", "equal")
+	t.Setenv("SYNC_RETRY_BUDGET", "10m0s")
+	t.Setenv("SYNC_RUN_RETRY_BUDGET", "30m0s")
+	t.Setenv("SYNC_DATA_DIR", "/env/data")
+	t.Setenv("SYNC_BREAKER_THRESHOLD", "4")
+	t.Setenv("SYNC_BREAKER_COOLDOWN", "1h0m0s")
+	t.Setenv("SYNC_RUN_DEADLINE", "4m30s")
+	t.Setenv("SYNC_REPORT", "/env/report.xml")
+	t.Setenv("SYNC_REPORT_FORMAT", "junit")
+	t.Setenv("SYNC_TERMINATION_LOG", "/env/termination-log")
+	t.Setenv("SYNC_PUSHGATEWAY_URL", "http://env-pushgateway:9091")
+	t.Setenv("SYNC_LOCK_BACKEND", "sql")
+	t.Setenv("SYNC_LOCK_DSN", "postgres://env-db/locks")
+	t.Setenv("SYNC_LOCK_TTL", "1m30s")
+	t.Setenv("SYNC_WAIT_FOR_LOCK", "5m0s")
+	t.Setenv("TRACEPARENT", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	t.Setenv("TRACESTATE", "vendor=env")
+	t.Setenv("SYNC_REQUIRE_TELEMETRY", "true")
+	t.Setenv("SYNC_SPAN_SPOOL_MAX_BYTES", "1048576")
 
 	cmd := newRootCmd()
 
@@ -25,10 +59,44 @@ func TestNewRootCmdEnv(t *testing.T) {
 		{"config", "env-pipeline.yaml"},
 		{"state", "env-state.json"},
 		{"otel-endpoint", "otel-env:4317"},
+		{"otel-protocol", "http/protobuf"},
+		{"otel-headers", "api-key=env"},
+		{"otel-certificate", "/env/ca.pem"},
+		{"otel-client-certificate", "/env/client.pem"},
+		{"otel-client-key", "/env/client-key.pem"},
+		{"otel-compression", "gzip"},
+		{"otel-timeout", "3s"},
+		{"traces-sampler", "parentbased_traceidratio"},
+		{"traces-sampler-arg", "0.1"},
+		{"telemetry-exporter", "stdout"},
+		{"telemetry-file", "/env/telemetry.jsonl"},
 		{"max-retries", "7"},
 		{"backoff-base", "3s"},
 		{"sling-binary", "/env/sling"},
 		{"sling-timeout", "45s"},
+		{"concurrency", "3"},
+		{"shutdown-grace", "15s"},
+		{"error-rules", "/env/rules.yaml"},
+		{"backoff-max", "2m0s"},
+		{"backoff-jitter", "equal"},
+		{"retry-budget", "10m0s"},
+		{"run-retry-budget", "30m0s"},
+		{"data-dir", "/env/data"},
+		{"breaker-threshold", "4"},
+		{"breaker-cooldown", "1h0m0s"},
+		{"run-deadline", "4m30s"},
+		{"report", "/env/report.xml"},
+		{"report-format", "junit"},
+		{"termination-log", "/env/termination-log"},
+		{"pushgateway-url", "http://env-pushgateway:9091"},
+		{"lock-backend", "sql"},
+		{"lock-dsn", "postgres://env-db/locks"},
+		{"lock-ttl", "1m30s"},
+		{"wait-for-lock", "5m0s"},
+		{"traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+		{"tracestate", "vendor=env"},
+		{"require-telemetry", "true"},
+		{"span-spool-max-bytes", "1048576"},
 	}
 
 	for _, tt := range tests {
@@ -38,3 +106,32 @@ func TestNewRootCmdEnv(t *testing.T) {
 		}
 	}
 }
+
+// TestDaemonCmdEnv verifies that the daemon flags default to their
+// environment variables.
+func TestDaemonCmdEnv(t *testing.T) {
+	t.Setenv("SYNC_SCHEDULE", "*/10 * * * *")
+	t.Setenv("SYNC_SCHEDULE_JITTER", "45s")
+	t.Setenv("SYNC_CATCHUP", "once")
+	t.Setenv("SYNC_HTTP_ADDR", ":9090")
+
+	cmd, _, err := newRootCmd().Find([]string{"daemon"})
+	if err != nil {
+		t.Fatalf("find daemon command: %v", err)
+	}
+	tests := []struct {
+		flag string
+		want string
+	}{
+		{"schedule", "*/10 * * * *"},
+		{"schedule-jitter", "45s"},
+		{"catch-up", "once"},
+		{"http-addr", ":9090"},
+	}
+	for _, tt := range tests {
+		got := cmd.Flags().Lookup(tt.flag).Value.String()
+		if got != tt.want {
+			t.Errorf("flag %s = %s, want %s", tt.flag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/breaker"
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/lock"
+	"sling-sync-wrapper/internal/logging"
+	"sling-sync-wrapper/internal/metrics"
+	"sling-sync-wrapper/internal/report"
+	"sling-sync-wrapper/internal/retry"
+	"sling-sync-wrapper/internal/schedule"
+	"sling-sync-wrapper/internal/tracing"
+)
+
+const (
+	// scheduleStateFile is the name of the daemon's schedule state in the
+	// data dir.
+	scheduleStateFile = "sling_schedule.json"
+	// globalScheduleJob names the job of the pipelines that follow the
+	// global schedule. Other jobs are named after their pipeline.
+	globalScheduleJob = "@global"
+)
+
+// scheduledJob is a set of pipelines that run together on one schedule.
+type scheduledJob struct {
+	name     string
+	spec     string
+	schedule schedule.Schedule
+	graph    *pipelineGraph
+}
+
+// scheduleJobs groups pipelines into jobs: those with a schedule option run
+// on their own, all others on the global schedule. Dependencies must stay
+// within a job because pipelines on different schedules do not run together.
+func scheduleJobs(cfg config.Config, pipelines []config.Pipeline) ([]*scheduledJob, error) {
+	jobOf := map[string]string{}
+	for _, p := range pipelines {
+		jobOf[p.Name] = globalScheduleJob
+		if p.Options.Schedule != "" {
+			jobOf[p.Name] = p.Name
+		}
+	}
+	for _, p := range pipelines {
+		for _, dep := range p.Options.DependsOn {
+			if jobOf[dep] != jobOf[p.Name] {
+				return nil, fmt.Errorf("pipeline %q depends on %q, which runs on a different schedule", p.Name, dep)
+			}
+		}
+	}
+
+	var jobs []*scheduledJob
+	var global []config.Pipeline
+	for _, p := range pipelines {
+		if p.Options.Schedule == "" {
+			global = append(global, p)
+			continue
+		}
+		s, err := schedule.Parse(p.Options.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline %q: %w", p.Name, err)
+		}
+		graph, err := buildGraph([]config.Pipeline{p})
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &scheduledJob{name: p.Name, spec: p.Options.Schedule, schedule: s, graph: graph})
+	}
+	if len(global) > 0 {
+		if cfg.Schedule == "" {
+			return nil, fmt.Errorf("%d pipelines have no schedule (set SYNC_SCHEDULE or a schedule wrapper option)", len(global))
+		}
+		s, err := schedule.Parse(cfg.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		graph, err := buildGraph(global)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append([]*scheduledJob{{name: globalScheduleJob, spec: cfg.Schedule, schedule: s, graph: graph}}, jobs...)
+	}
+	return jobs, nil
+}
+
+// daemon runs scheduled jobs until its context is cancelled. The tracer,
+// metrics, locker and health store are shared by all runs for the process
+// lifetime.
+type daemon struct {
+	cfg         config.Config
+	tracer      trace.Tracer
+	classifier  *errorClassifier
+	health      *breaker.Store
+	metrics     *metrics.Metrics
+	instruments *tracing.Instruments
+	locker      lock.Locker
+	state       *schedule.State
+	catchUp     schedule.CatchUp
+	// degraded is set when telemetry could not be set up.
+	degraded bool
+
+	// reportMu serializes report writes of jobs finishing at the same time.
+	reportMu sync.Mutex
+
+	// ctx is the context of runs triggered through the HTTP API.
+	ctx       context.Context
+	startedAt time.Time
+	ready     atomic.Bool
+	board     *statusBoard
+	// pipelines holds all pipelines by name, pipelineNames their names in
+	// load order.
+	pipelines     map[string]config.Pipeline
+	pipelineNames []string
+	// triggered tracks runs started through the HTTP API.
+	triggered sync.WaitGroup
+}
+
+// newRunner returns a runner sharing the daemon's long-lived state.
+func (d *daemon) newRunner() *runner {
+	return &runner{
+		tracer:      d.tracer,
+		classifier:  d.classifier,
+		retryBudget: retry.NewBudget(d.cfg.RunRetryBudget),
+		health:      d.health,
+		metrics:     d.metrics,
+		instruments: d.instruments,
+		locker:      d.locker,
+		board:       d.board,
+	}
+}
+
+// runDaemon runs the configured pipelines on their schedules until ctx is
+// cancelled, then waits for running pipelines to shut down.
+func runDaemon(ctx context.Context, cfg config.Config) error {
+	logger := logging.FromContext(ctx)
+
+	pipelines, err := config.LoadPipelines(cfg)
+	if err != nil {
+		return &configError{fmt.Errorf("load pipelines: %w", err)}
+	}
+	if _, err := buildGraph(pipelines); err != nil {
+		return &configError{fmt.Errorf("load pipelines: %w", err)}
+	}
+	jobs, err := scheduleJobs(cfg, pipelines)
+	if err != nil {
+		return &configError{fmt.Errorf("load schedules: %w", err)}
+	}
+	catchUp, err := schedule.ParseCatchUp(cfg.CatchUp)
+	if err != nil {
+		return &configError{err}
+	}
+	if cfg.ScheduleJitter < 0 {
+		return &configError{fmt.Errorf("schedule jitter must not be negative, got %s", cfg.ScheduleJitter)}
+	}
+	if cfg.ReportFile != "" {
+		if err := report.ValidateFormat(cfg.ReportFormat); err != nil {
+			return &configError{fmt.Errorf("invalid report format: %w", err)}
+		}
+	}
+	classifier, err := loadErrorClassifier(cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := retry.ParseJitter(cfg.BackoffJitter); err != nil {
+		return &configError{fmt.Errorf("invalid backoff jitter: %w", err)}
+	}
+	state, err := schedule.OpenState(filepath.Join(config.DataDir(cfg), scheduleStateFile))
+	if err != nil {
+		return err
+	}
+
+	tracer, shutdown, degraded, err := initTracing(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), telemetryShutdownTimeout)
+		defer cancel()
+		if err := shutdown(shutdownCtx); err != nil {
+			logger.Error("failed to shut down tracer", "err", err)
+		}
+	}()
+
+	locker, closeLocker, err := openLocker(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeLocker()
+
+	health := openHealthStore(ctx, cfg)
+	d := &daemon{
+		cfg:         cfg,
+		tracer:      tracer,
+		degraded:    degraded,
+		classifier:  classifier,
+		health:      health,
+		metrics:     newMetrics(cfg, health),
+		instruments: newInstruments(ctx, cfg),
+		locker:      locker,
+		state:       state,
+		catchUp:     catchUp,
+		ctx:         ctx,
+		startedAt:   time.Now(),
+		board:       newStatusBoard(),
+		pipelines:   map[string]config.Pipeline{},
+	}
+	for _, p := range pipelines {
+		d.pipelines[p.Name] = p
+		d.pipelineNames = append(d.pipelineNames, p.Name)
+	}
+
+	var wg sync.WaitGroup
+	if cfg.HTTPAddr != "" {
+		ln, err := net.Listen("tcp", cfg.HTTPAddr)
+		if err != nil {
+			return fmt.Errorf("start HTTP server: %w", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.serve(ctx, ln)
+		}()
+	}
+
+	logger.Info("daemon started", "jobs", len(jobs), "catch_up", catchUp, "schedule_jitter", cfg.ScheduleJitter)
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.loop(ctx, job)
+		}()
+	}
+	d.ready.Store(true)
+	<-ctx.Done()
+	d.ready.Store(false)
+	wg.Wait()
+	d.triggered.Wait()
+	logger.Info("daemon stopped")
+	return nil
+}
+
+// loop runs job on its schedule until ctx is cancelled. Runs of a job never
+// overlap: activations that pass while it is still running are missed and
+// handled according to the catch-up policy.
+func (d *daemon) loop(ctx context.Context, job *scheduledJob) {
+	logger := logging.FromContext(ctx).With("schedule_job", job.name, "schedule", job.spec)
+	ctx = logging.NewContext(ctx, logger)
+
+	last, ok := d.state.LastRun(job.name)
+	status := scheduleStatus{Name: job.name, Schedule: job.spec}
+	if ok {
+		status.LastRun = last
+	} else {
+		// Nothing was missed before the first start.
+		last = time.Now()
+	}
+	for {
+		next, missed := schedule.Next(job.schedule, last, time.Now(), d.catchUp)
+		if missed > 0 {
+			logger.Warn("scheduled runs missed", "missed", missed, "catch_up", d.catchUp)
+		}
+		runAt := next
+		if d.cfg.ScheduleJitter > 0 {
+			runAt = runAt.Add(rand.N(d.cfg.ScheduleJitter))
+		}
+		wait := time.Until(runAt)
+		logger.Info("next run scheduled", "next_run", runAt, "wait_seconds", wait.Seconds())
+		status.NextRun = runAt
+		d.board.scheduled(status)
+		if err := sleepFunc(ctx, wait); err != nil {
+			return
+		}
+
+		err := d.runJob(ctx, job)
+		if ctx.Err() != nil {
+			// Interrupted runs are not recorded so that catch-up can
+			// repeat them after a restart.
+			return
+		}
+		last = next
+		status.LastRun = last
+		if err := d.state.Record(job.name, last); err != nil {
+			logger.Warn("failed to write schedule state", "err", err)
+		}
+		if err != nil {
+			logger.Error("scheduled run failed", "err", err)
+		}
+	}
+}
+
+// runJob executes the pipelines of job once.
+func (d *daemon) runJob(ctx context.Context, job *scheduledJob) (err error) {
+	start := time.Now()
+	rep := &report.Report{MissionClusterID: d.cfg.MissionClusterID, SyncMode: d.cfg.SyncMode, StartedAt: start,
+		TelemetryDegraded: d.degraded}
+	defer func() {
+		d.reportMu.Lock()
+		defer d.reportMu.Unlock()
+		writeReports(ctx, d.cfg, rep, err)
+		logging.FromContext(ctx).Info("scheduled run finished", "status", rep.Status,
+			"duration_seconds", rep.DurationSeconds, "pipelines", rep.Counts())
+	}()
+
+	return d.newRunner().execute(ctx, d.cfg, job.graph, start, rep)
+}
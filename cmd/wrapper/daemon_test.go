@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/schedule"
+	"sling-sync-wrapper/internal/tracing"
+)
+
+func TestScheduleJobs(t *testing.T) {
+	pipelines := []config.Pipeline{
+		{Name: "dims", Path: "dims.yaml"},
+		{Name: "facts", Path: "facts.yaml", Options: config.PipelineOptions{DependsOn: []string{"dims"}}},
+		{Name: "telemetry", Path: "telemetry.yaml", Options: config.PipelineOptions{Schedule: "1m"}},
+	}
+	jobs, err := scheduleJobs(config.Config{Schedule: "*/15 * * * *"}, pipelines)
+	if err != nil {
+		t.Fatalf("scheduleJobs() error = %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].name != globalScheduleJob || len(jobs[0].graph.nodes) != 2 ||
+		jobs[1].name != "telemetry" || jobs[1].spec != "1m" {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+
+	if _, err := scheduleJobs(config.Config{}, pipelines); err == nil || !strings.Contains(err.Error(), "no schedule") {
+		t.Fatalf("expected missing schedule error, got %v", err)
+	}
+
+	pipelines[1].Options.Schedule = "5m"
+	if _, err := scheduleJobs(config.Config{Schedule: "1h"}, pipelines); err == nil || !strings.Contains(err.Error(), "different schedule") {
+		t.Fatalf("expected cross-schedule dependency error, got %v", err)
+	}
+}
+
+// stubDaemonSleep makes the daemon's waits return at once and records them.
+// The context is cancelled at the wait following the given number of runs.
+func stubDaemonSleep(t *testing.T, runs int, cancel context.CancelFunc) func() []time.Duration {
+	t.Helper()
+	var mu sync.Mutex
+	var waits []time.Duration
+	sleepFunc = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		waits = append(waits, d)
+		if len(waits) > runs {
+			cancel()
+			return ctx.Err()
+		}
+		return nil
+	}
+	t.Cleanup(func() { sleepFunc = sleepContext })
+	return func() []time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]time.Duration(nil), waits...)
+	}
+}
+
+func TestDaemonRunsJobsWithOneTracer(t *testing.T) {
+	sr := recordSpans(t)
+	inits := 0
+	initTracing := tracingInitFunc
+	tracingInitFunc = func(ctx context.Context, opts tracing.Options) (trace.Tracer, func(context.Context) error, error) {
+		inits++
+		return initTracing(ctx, opts)
+	}
+
+	dir := writePipelines(t, "a.yaml", "b.yaml")
+	var mu sync.Mutex
+	ran := map[string]int{}
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran[filepath.Base(run.Pipeline)]++
+		return 1, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	ctx, cancel := context.WithCancel(testContext())
+	defer cancel()
+	stubDaemonSleep(t, 3, cancel)
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", MaxRetries: 1,
+		Concurrency: 1, DataDir: t.TempDir(), Schedule: "10m", CatchUp: "skip"}
+	if err := runDaemon(ctx, cfg); err != nil {
+		t.Fatalf("runDaemon() error = %v", err)
+	}
+	if inits != 1 {
+		t.Fatalf("tracing initialized %d times, want once", inits)
+	}
+	if ran["a.yaml"] != 3 || ran["b.yaml"] != 3 {
+		t.Fatalf("runs = %v, want 3 of each pipeline", ran)
+	}
+	if got := len(pipelineSpans(sr)); got != 6 {
+		t.Fatalf("recorded %d pipeline spans, want 6", got)
+	}
+
+	state, err := schedule.OpenState(filepath.Join(cfg.DataDir, scheduleStateFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := state.LastRun(globalScheduleJob); !ok {
+		t.Fatal("last run of the global job not recorded")
+	}
+}
+
+func TestDaemonCatchUp(t *testing.T) {
+	tests := []struct {
+		catchUp string
+		wantDue bool
+	}{
+		{"skip", false},
+		{"once", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.catchUp, func(t *testing.T) {
+			stubTracing(t)
+			dir := writePipelines(t, "a.yaml")
+			runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+				return 0, nil
+			}
+			defer func() { runSlingOnceFunc = runSlingOnce }()
+
+			// The last run was an hour ago, so five activations were missed.
+			dataDir := t.TempDir()
+			state := `{"@global": "` + time.Now().Add(-time.Hour).Format(time.RFC3339Nano) + `"}`
+			if err := os.WriteFile(filepath.Join(dataDir, scheduleStateFile), []byte(state), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(testContext())
+			defer cancel()
+			waits := stubDaemonSleep(t, 1, cancel)
+
+			cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", MaxRetries: 1,
+				Concurrency: 1, DataDir: dataDir, Schedule: "@every 11m", CatchUp: tt.catchUp}
+			if err := runDaemon(ctx, cfg); err != nil {
+				t.Fatalf("runDaemon() error = %v", err)
+			}
+			first := waits()[0]
+			if due := first <= 0; due != tt.wantDue {
+				t.Fatalf("first wait = %s, want due immediately: %v", first, tt.wantDue)
+			}
+		})
+	}
+}
+
+func TestDaemonRejectsInvalidConfig(t *testing.T) {
+	stubTracing(t)
+	dir := writePipelines(t, "a.yaml")
+	tests := map[string]config.Config{
+		"no schedule":  {PipelineDir: dir},
+		"bad schedule": {PipelineDir: dir, Schedule: "every day", CatchUp: "skip"},
+		"bad catch-up": {PipelineDir: dir, Schedule: "10m", CatchUp: "all"},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := runDaemon(testContext(), cfg)
+			if code := exitCode(err); code != exitConfigError {
+				t.Fatalf("exit code = %d (%v), want %d", code, err, exitConfigError)
+			}
+		})
+	}
+}
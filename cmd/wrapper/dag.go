@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sling-sync-wrapper/internal/config"
+)
+
+// pipelineNode is a pipeline together with its edges in the dependency graph.
+type pipelineNode struct {
+	index      int
+	pipeline   config.Pipeline
+	deps       []*pipelineNode
+	dependents []*pipelineNode
+}
+
+// pipelineGraph is a validated, acyclic graph of pipelines.
+type pipelineGraph struct {
+	nodes []*pipelineNode
+}
+
+// buildGraph links pipelines by their depends_on declarations. It fails for
+// unknown dependencies and for cycles so that broken configurations are
+// rejected before any pipeline runs.
+func buildGraph(pipelines []config.Pipeline) (*pipelineGraph, error) {
+	g := &pipelineGraph{}
+	byName := make(map[string]*pipelineNode, len(pipelines))
+	for i, p := range pipelines {
+		if _, ok := byName[p.Name]; ok {
+			return nil, fmt.Errorf("duplicate pipeline name %q", p.Name)
+		}
+		n := &pipelineNode{index: i, pipeline: p}
+		byName[p.Name] = n
+		g.nodes = append(g.nodes, n)
+	}
+
+	for _, n := range g.nodes {
+		for _, dep := range n.pipeline.Options.DependsOn {
+			d, ok := byName[dep]
+			if !ok {
+				return nil, fmt.Errorf("pipeline %q depends on unknown pipeline %q", n.pipeline.Name, dep)
+			}
+			n.deps = append(n.deps, d)
+			d.dependents = append(d.dependents, n)
+		}
+	}
+
+	if cycle := g.findCycle(); cycle != nil {
+		return nil, fmt.Errorf("pipeline dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return g, nil
+}
+
+// findCycle returns the names along a dependency cycle, or nil if the graph
+// is acyclic.
+func (g *pipelineGraph) findCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*pipelineNode]int, len(g.nodes))
+	var stack []*pipelineNode
+
+	var visit func(n *pipelineNode) []string
+	visit = func(n *pipelineNode) []string {
+		state[n] = visiting
+		stack = append(stack, n)
+		for _, d := range n.deps {
+			switch state[d] {
+			case visiting:
+				var cycle []string
+				for i := len(stack) - 1; i >= 0; i-- {
+					cycle = append([]string{stack[i].pipeline.Name}, cycle...)
+					if stack[i] == d {
+						break
+					}
+				}
+				return append(cycle, d.pipeline.Name)
+			case unvisited:
+				if cycle := visit(d); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[n] = visited
+		return nil
+	}
+
+	for _, n := range g.nodes {
+		if state[n] == unvisited {
+			if cycle := visit(n); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// upstreamFailedError is recorded for pipelines that were skipped because one
+// of their dependencies failed.
+type upstreamFailedError struct {
+	upstream string
+}
+
+func (e *upstreamFailedError) Error() string {
+	return fmt.Sprintf("skipped: upstream pipeline %q failed", e.upstream)
+}
+
+// nodeResult carries the outcome of a single node execution back to the
+// scheduler loop.
+type nodeResult struct {
+	node *pipelineNode
+	err  error
+}
+
+// executeGraph runs fn for every node of g, starting a node only after all of
+// its dependencies succeeded and running at most concurrency nodes at once.
+// Nodes that are not run are passed to skip together with the reason: an
+// *upstreamFailedError for nodes downstream of a failure, or the cause of the
+// context cancellation for nodes that were still waiting when ctx was done.
+// The returned slice holds the error of each node, indexed like g.nodes.
+func executeGraph(ctx context.Context, g *pipelineGraph, concurrency int,
+	fn func(context.Context, *pipelineNode) error,
+	skip func(context.Context, *pipelineNode, error),
+) []error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	errs := make([]error, len(g.nodes))
+	pending := make(map[*pipelineNode]int, len(g.nodes))
+	settled := make(map[*pipelineNode]bool, len(g.nodes))
+	var ready []*pipelineNode
+	for _, n := range g.nodes {
+		pending[n] = len(n.deps)
+		if len(n.deps) == 0 {
+			ready = append(ready, n)
+		}
+	}
+
+	settle := func(n *pipelineNode, err error) {
+		settled[n] = true
+		errs[n.index] = err
+		skip(ctx, n, err)
+	}
+
+	results := make(chan nodeResult)
+	running := 0
+	for {
+		for running < concurrency && len(ready) > 0 && ctx.Err() == nil {
+			n := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				results <- nodeResult{node: n, err: fn(ctx, n)}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		r := <-results
+		running--
+		settled[r.node] = true
+		errs[r.node.index] = r.err
+
+		if ctx.Err() != nil {
+			// Everything still waiting is settled as cancelled below.
+			continue
+		}
+
+		if r.err != nil {
+			// Every transitive dependent of a failed node is still waiting,
+			// so it can be settled as skipped right away. Dependents of a
+			// deferred node did not fail either and are deferred with it.
+			var reason error = &upstreamFailedError{upstream: r.node.pipeline.Name}
+			var deferred *deferredError
+			if errors.As(r.err, &deferred) {
+				reason = &deferredError{upstream: r.node.pipeline.Name}
+			}
+			queue := append([]*pipelineNode(nil), r.node.dependents...)
+			for len(queue) > 0 {
+				d := queue[0]
+				queue = queue[1:]
+				if settled[d] {
+					continue
+				}
+				settle(d, reason)
+				queue = append(queue, d.dependents...)
+			}
+			continue
+		}
+
+		for _, d := range r.node.dependents {
+			pending[d]--
+			if pending[d] == 0 && !settled[d] {
+				ready = append(ready, d)
+			}
+		}
+		sort.Slice(ready, func(i, j int) bool { return ready[i].index < ready[j].index })
+	}
+
+	// Only a done context leaves nodes unsettled.
+	if ctx.Err() == nil {
+		return errs
+	}
+	notStarted := fmt.Errorf("not started: %w", ctx.Err())
+	if cause := context.Cause(ctx); cause != ctx.Err() {
+		notStarted = fmt.Errorf("not started: %w: %w", ctx.Err(), cause)
+	}
+	for _, n := range g.nodes {
+		if !settled[n] {
+			settle(n, notStarted)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"sling-sync-wrapper/internal/config"
+)
+
+func testPipelines(deps map[string][]string, names ...string) []config.Pipeline {
+	var pipelines []config.Pipeline
+	for _, name := range names {
+		pipelines = append(pipelines, config.Pipeline{
+			Name:    name,
+			Path:    name + ".yaml",
+			Options: config.PipelineOptions{DependsOn: deps[name]},
+		})
+	}
+	return pipelines
+}
+
+func TestBuildGraphCycle(t *testing.T) {
+	pipelines := testPipelines(map[string][]string{
+		"a": {"c"},
+		"b": {"a"},
+		"c": {"b"},
+	}, "a", "b", "c")
+
+	_, err := buildGraph(pipelines)
+	if err == nil {
+		t.Fatalf("expected cycle error")
+	}
+	if !strings.Contains(err.Error(), "a -> c -> b -> a") {
+		t.Fatalf("unexpected cycle error: %v", err)
+	}
+}
+
+func TestBuildGraphUnknownDependency(t *testing.T) {
+	pipelines := testPipelines(map[string][]string{"a": {"missing"}}, "a")
+	if _, err := buildGraph(pipelines); err == nil {
+		t.Fatalf("expected unknown dependency error")
+	}
+}
+
+func TestExecuteGraphOrder(t *testing.T) {
+	pipelines := testPipelines(map[string][]string{
+		"facts":   {"dims", "lookups"},
+		"summary": {"facts"},
+	}, "dims", "facts", "lookups", "summary")
+	g, err := buildGraph(pipelines)
+	if err != nil {
+		t.Fatalf("buildGraph: %v", err)
+	}
+
+	var mu sync.Mutex
+	finished := map[string]bool{}
+	errs := executeGraph(context.Background(), g, 4,
+		func(ctx context.Context, n *pipelineNode) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, d := range n.deps {
+				if !finished[d.pipeline.Name] {
+					t.Errorf("%s started before dependency %s finished", n.pipeline.Name, d.pipeline.Name)
+				}
+			}
+			finished[n.pipeline.Name] = true
+			return nil
+		},
+		func(ctx context.Context, n *pipelineNode, err error) {
+			t.Errorf("unexpected skip of %s: %v", n.pipeline.Name, err)
+		},
+	)
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("pipeline %s: %v", g.nodes[i].pipeline.Name, err)
+		}
+	}
+	if len(finished) != 4 {
+		t.Fatalf("expected 4 pipelines to run, got %v", finished)
+	}
+}
+
+func TestExecuteGraphSkipsDownstream(t *testing.T) {
+	pipelines := testPipelines(map[string][]string{
+		"facts":   {"dims"},
+		"summary": {"facts", "other"},
+	}, "dims", "facts", "other", "summary")
+	g, err := buildGraph(pipelines)
+	if err != nil {
+		t.Fatalf("buildGraph: %v", err)
+	}
+
+	var mu sync.Mutex
+	var ran []string
+	skipped := map[string]string{}
+	errs := executeGraph(context.Background(), g, 2,
+		func(ctx context.Context, n *pipelineNode) error {
+			mu.Lock()
+			ran = append(ran, n.pipeline.Name)
+			mu.Unlock()
+			if n.pipeline.Name == "dims" {
+				return fmt.Errorf("boom")
+			}
+			return nil
+		},
+		func(ctx context.Context, n *pipelineNode, err error) {
+			var upstream *upstreamFailedError
+			if !errors.As(err, &upstream) {
+				t.Errorf("unexpected skip reason for %s: %v", n.pipeline.Name, err)
+				return
+			}
+			skipped[n.pipeline.Name] = upstream.upstream
+		},
+	)
+
+	if len(ran) != 2 {
+		t.Fatalf("expected dims and other to run, got %v", ran)
+	}
+	if skipped["facts"] != "dims" || skipped["summary"] != "dims" || len(skipped) != 2 {
+		t.Fatalf("unexpected skipped pipelines: %v", skipped)
+	}
+	for i, n := range g.nodes {
+		if (errs[i] == nil) != (n.pipeline.Name == "other") {
+			t.Errorf("pipeline %s: unexpected error %v", n.pipeline.Name, errs[i])
+		}
+	}
+}
+
+func TestExecuteGraphCancelled(t *testing.T) {
+	pipelines := testPipelines(map[string][]string{"b": {"a"}}, "a", "b", "c")
+	g, err := buildGraph(pipelines)
+	if err != nil {
+		t.Fatalf("buildGraph: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var ran []string
+	cancelled := map[string]bool{}
+	errs := executeGraph(ctx, g, 1,
+		func(ctx context.Context, n *pipelineNode) error {
+			ran = append(ran, n.pipeline.Name)
+			cancel()
+			return ctx.Err()
+		},
+		func(ctx context.Context, n *pipelineNode, err error) {
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("unexpected skip reason for %s: %v", n.pipeline.Name, err)
+			}
+			cancelled[n.pipeline.Name] = true
+		},
+	)
+
+	if len(ran) != 1 || ran[0] != "a" {
+		t.Fatalf("expected only a to run, got %v", ran)
+	}
+	if !cancelled["b"] || !cancelled["c"] || len(cancelled) != 2 {
+		t.Fatalf("unexpected cancelled pipelines: %v", cancelled)
+	}
+	for i, err := range errs {
+		if statusFromErr(err) != "cancelled" {
+			t.Errorf("pipeline %s: status %s, want cancelled", g.nodes[i].pipeline.Name, statusFromErr(err))
+		}
+	}
+}
+
+func TestRunRecordsSkippedSpans(t *testing.T) {
+	sr := recordSpans(t)
+
+	dir := writePipelines(t, "dims.yaml", "facts.yaml")
+	os.WriteFile(filepath.Join(dir, "facts.wrapper.yaml"), []byte("depends_on: [dims]\n"), 0644)
+
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		return 0, fmt.Errorf("boom")
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", DataDir: t.TempDir(), SyncMode: "normal", MaxRetries: 1, BackoffBase: time.Millisecond}
+	if err := run(testContext(), cfg); err == nil {
+		t.Fatalf("expected error from run")
+	}
+
+	statuses := spanStatuses(sr)
+	if statuses["dims.yaml"] != "failed" || statuses["facts.yaml"] != "skipped" {
+		t.Fatalf("unexpected span statuses: %v", statuses)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"sling-sync-wrapper/internal/config"
+)
+
+// errRunDeadline is wrapped by the errors of pipelines that were interrupted
+// or deferred because the run deadline was reached.
+var errRunDeadline = errors.New("run deadline exceeded")
+
+// deferredError is recorded for pipelines that were not started because they
+// would not finish before the run deadline.
+type deferredError struct {
+	// expected and remaining are set when the pipeline was judged not to fit
+	// into the time left.
+	expected  time.Duration
+	remaining time.Duration
+	// upstream is set when a dependency of the pipeline was deferred.
+	upstream string
+}
+
+func (e *deferredError) Error() string {
+	switch {
+	case e.upstream != "":
+		return fmt.Sprintf("deferred: upstream pipeline %q was deferred", e.upstream)
+	case e.expected > 0:
+		return fmt.Sprintf("deferred: expected duration %s exceeds remaining run time %s",
+			e.expected.Round(time.Second), e.remaining.Round(time.Second))
+	}
+	return "deferred: run deadline reached"
+}
+
+func (e *deferredError) Unwrap() error { return errRunDeadline }
+
+// checkDeadline returns a *deferredError if pipeline is not expected to
+// finish before the run deadline. Pipelines without a known duration are
+// started as long as the deadline has not passed.
+func (r *runner) checkDeadline(pipeline config.Pipeline) error {
+	if r.deadline.IsZero() {
+		return nil
+	}
+	expected := pipeline.Options.ExpectedDuration
+	if expected == 0 && r.health != nil {
+		expected = r.health.LastDuration(pipeline.Name)
+	}
+	remaining := time.Until(r.deadline)
+	if remaining <= 0 || expected > remaining {
+		return &deferredError{expected: expected, remaining: remaining}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/breaker"
+	"sling-sync-wrapper/internal/config"
+)
+
+func TestRunDefersPipelinesThatDoNotFit(t *testing.T) {
+	sr := recordSpans(t)
+	dir := writePipelines(t, "small.yaml", "huge.yaml", "after_huge.yaml")
+	os.WriteFile(filepath.Join(dir, "huge.wrapper.yaml"), []byte("expected_duration: 2h\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "after_huge.wrapper.yaml"), []byte("depends_on: [huge]\n"), 0644)
+
+	var mu sync.Mutex
+	var ran []string
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		mu.Lock()
+		ran = append(ran, filepath.Base(run.Pipeline))
+		mu.Unlock()
+		return 0, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", SyncMode: "normal",
+		MaxRetries: 1, Concurrency: 1, DataDir: t.TempDir(), RunDeadline: time.Hour}
+	err := run(testContext(), cfg)
+	if !errors.Is(err, errRunDeadline) {
+		t.Fatalf("expected run deadline error, got %v", err)
+	}
+	if len(ran) != 1 || ran[0] != "small.yaml" {
+		t.Fatalf("expected only small.yaml to run, got %v", ran)
+	}
+	statuses := spanStatuses(sr)
+	if statuses["small.yaml"] != "success" || statuses["huge.yaml"] != "deferred" || statuses["after_huge.yaml"] != "deferred" {
+		t.Fatalf("unexpected span statuses: %v", statuses)
+	}
+}
+
+func TestRunDeadlineUsesLastDuration(t *testing.T) {
+	recordSpans(t)
+	dir := writePipelines(t, "slow.yaml")
+	dataDir := t.TempDir()
+	store, _ := breaker.Open(filepath.Join(dataDir, healthStoreFile), 0, 0)
+	store.RecordSuccess("slow", time.Now(), 3*time.Hour)
+
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		t.Errorf("pipeline should have been deferred")
+		return 0, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", SyncMode: "normal",
+		MaxRetries: 1, Concurrency: 1, DataDir: dataDir, RunDeadline: time.Hour}
+	var deferred *deferredError
+	if err := run(testContext(), cfg); !errors.As(err, &deferred) || deferred.expected != 3*time.Hour {
+		t.Fatalf("expected deferral based on last duration, got %v", err)
+	}
+}
+
+func TestRunDeadlineInterruptsRunningPipeline(t *testing.T) {
+	sr := recordSpans(t)
+	dir := writePipelines(t, "a.yaml", "b.yaml")
+
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	dataDir := t.TempDir()
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", SyncMode: "normal",
+		MaxRetries: 3, BackoffBase: time.Millisecond, Concurrency: 1, DataDir: dataDir,
+		BreakerThreshold: 1, BreakerCooldown: time.Hour, RunDeadline: 50 * time.Millisecond}
+	if err := run(testContext(), cfg); !errors.Is(err, errRunDeadline) {
+		t.Fatalf("expected run deadline error, got %v", err)
+	}
+	statuses := spanStatuses(sr)
+	if statuses["a.yaml"] != "deadline_exceeded" || statuses["b.yaml"] != "deferred" {
+		t.Fatalf("unexpected span statuses: %v", statuses)
+	}
+
+	// Running out of time says nothing about the pipeline's health.
+	store, _ := breaker.Open(filepath.Join(dataDir, healthStoreFile), 1, time.Hour)
+	if _, ok, _ := store.Allow("a", time.Now()); !ok {
+		t.Fatalf("deadline interruption opened the circuit")
+	}
+}
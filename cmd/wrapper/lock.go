@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/lock"
+	"sling-sync-wrapper/internal/logging"
+)
+
+const (
+	// lockDir is the directory of the file lock backend in the data dir.
+	lockDir = "locks"
+	// lockTable is the table of the SQL lock backend.
+	lockTable = "sling_sync_locks"
+	// lockReleaseTimeout bounds how long releasing a lock may take.
+	lockReleaseTimeout = 10 * time.Second
+)
+
+var (
+	// errLockHeld is returned for pipelines whose lock is held by another
+	// invocation.
+	errLockHeld = lock.ErrHeld
+	// errLockLost is the cause of cancelling a pipeline whose lock could
+	// not be kept.
+	errLockLost = errors.New("run lock lost")
+)
+
+// lockDrivers maps SQL lock DSN schemes to database/sql drivers.
+var lockDrivers = map[string]string{
+	"postgres":   "postgres",
+	"postgresql": "postgres",
+	"sqlite3":    "sqlite3",
+}
+
+// openLocker returns the locker configured by cfg and a function closing it.
+// The locker is nil for noop runs and when locking is disabled.
+func openLocker(ctx context.Context, cfg config.Config) (lock.Locker, func() error, error) {
+	noClose := func() error { return nil }
+	if cfg.SyncMode == "noop" {
+		return nil, noClose, nil
+	}
+	switch cfg.LockBackend {
+	case "", "none":
+		return nil, noClose, nil
+	case "file":
+		return lock.NewFileLocker(filepath.Join(config.DataDir(cfg), lockDir)), noClose, nil
+	case "sql":
+		driver, dsn, err := parseLockDSN(cfg.LockDSN)
+		if err != nil {
+			return nil, nil, &configError{err}
+		}
+		db, err := sql.Open(driver, dsn)
+		if err != nil {
+			return nil, nil, &configError{fmt.Errorf("open lock database: %w", err)}
+		}
+		locker, err := lock.NewSQLLocker(ctx, db, lockTable, lockHolder(), cfg.LockTTL)
+		if err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("open lock database: %w", err)
+		}
+		return locker, db.Close, nil
+	default:
+		return nil, nil, &configError{fmt.Errorf("invalid lock backend %q (want file, sql or none)", cfg.LockBackend)}
+	}
+}
+
+// parseLockDSN returns the driver and data source name for a SQL lock DSN.
+// Postgres URLs are passed on as is; sqlite3://path opens a SQLite file in cgo
+// builds.
+func parseLockDSN(dsn string) (driver, source string, err error) {
+	if dsn == "" {
+		return "", "", errors.New("the sql lock backend requires a lock DSN")
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid lock DSN: %w", err)
+	}
+	driver, ok := lockDrivers[u.Scheme]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported lock DSN scheme %q", u.Scheme)
+	}
+	if driver == "sqlite3" {
+		if !sqliteLocks {
+			return "", "", errors.New("sqlite3 lock DSNs need a cgo build of the wrapper")
+		}
+		return driver, strings.TrimPrefix(dsn, u.Scheme+"://"), nil
+	}
+	return driver, dsn, nil
+}
+
+// lockHolder identifies this process in the lock table.
+func lockHolder() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s/%d/%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+// lockKey returns the lock key of pipeline. Runs only conflict when they
+// sync the same pipeline against the same state.
+func lockKey(cfg config.Config, pipeline string) string {
+	return strings.TrimSuffix(cfg.StateLocation, "/") + "/" + pipeline
+}
+
+// acquireLock takes the lock of pipeline, waiting up to cfg.WaitForLock for
+// another holder to release it. The returned context is cancelled if the lock
+// is lost while the pipeline runs; release must be called once it is done.
+func (r *runner) acquireLock(ctx context.Context, cfg config.Config, pipeline string, span trace.Span) (context.Context, func(), error) {
+	if r.locker == nil {
+		return ctx, func() {}, nil
+	}
+	key := lockKey(cfg, pipeline)
+	span.SetAttributes(attribute.String("lock.key", key))
+	start := time.Now()
+	lease, err := lock.Acquire(ctx, r.locker, key, cfg.WaitForLock)
+	span.SetAttributes(attribute.Float64("lock.wait_seconds", time.Since(start).Seconds()))
+	if err != nil {
+		if errors.Is(err, lock.ErrHeld) || ctx.Err() != nil {
+			return ctx, nil, err
+		}
+		return ctx, nil, fmt.Errorf("acquire lock: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-lease.Lost():
+			logging.FromContext(ctx).ErrorContext(ctx, "run lock lost, stopping pipeline", "lock_key", key)
+			cancel(errLockLost)
+		case <-ctx.Done():
+		}
+	}()
+	release := func() {
+		cancel(nil)
+		releaseCtx, done := context.WithTimeout(context.WithoutCancel(ctx), lockReleaseTimeout)
+		defer done()
+		if err := lease.Release(releaseCtx); err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "failed to release run lock", "lock_key", key, "err", err)
+		}
+	}
+	return ctx, release, nil
+}
@@ -0,0 +1,7 @@
+//go:build !cgo
+
+package main
+
+// sqliteLocks reports whether the SQLite driver of the sql lock backend is
+// built in. It needs cgo.
+const sqliteLocks = false
@@ -0,0 +1,9 @@
+//go:build cgo
+
+package main
+
+import _ "github.com/mattn/go-sqlite3"
+
+// sqliteLocks reports whether the SQLite driver of the sql lock backend is
+// built in. It needs cgo.
+const sqliteLocks = true
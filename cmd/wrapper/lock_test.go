@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/lock"
+	"sling-sync-wrapper/internal/tracing"
+)
+
+func TestRunSkipsPipelineWithHeldLock(t *testing.T) {
+	sr := recordSpans(t)
+
+	dir := writePipelines(t, "a.yaml", "b.yaml")
+	var ran []string
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		ran = append(ran, filepath.Base(run.Pipeline))
+		return 0, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	dataDir := t.TempDir()
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", SyncMode: "normal",
+		MaxRetries: 1, Concurrency: 1, DataDir: dataDir, LockBackend: "file"}
+
+	// Another invocation is syncing pipeline a against the same state.
+	held, err := lock.NewFileLocker(filepath.Join(dataDir, lockDir)).TryAcquire(context.Background(), lockKey(cfg, "a"))
+	if err != nil {
+		t.Fatalf("acquire lock: %v", err)
+	}
+	defer held.Release(context.Background())
+
+	err = run(testContext(), cfg)
+	if code := exitCode(err); code != exitLockHeld {
+		t.Fatalf("exit code = %d (%v), want %d", code, err, exitLockHeld)
+	}
+	if len(ran) != 1 || ran[0] != "b.yaml" {
+		t.Fatalf("ran %v, want only b.yaml", ran)
+	}
+	statuses := spanStatuses(sr)
+	if statuses["a.yaml"] != "lock_held" || statuses["b.yaml"] != "success" {
+		t.Fatalf("statuses = %v, want a lock_held and b success", statuses)
+	}
+
+	// Once released, the next run syncs the pipeline again.
+	held.Release(context.Background())
+	if err := run(testContext(), cfg); err != nil {
+		t.Fatalf("run after release: %v", err)
+	}
+}
+
+// lostLocker hands out leases that are lost right away.
+type lostLocker struct{}
+
+func (lostLocker) TryAcquire(ctx context.Context, key string) (lock.Lease, error) {
+	lost := make(chan struct{})
+	close(lost)
+	return lostLease(lost), nil
+}
+
+type lostLease chan struct{}
+
+func (l lostLease) Lost() <-chan struct{}             { return l }
+func (l lostLease) Release(ctx context.Context) error { return nil }
+
+func TestRunPipelineStopsWhenLockLost(t *testing.T) {
+	sr := recordSpans(t)
+	tracer, _, _ := tracingInitFunc(context.Background(), tracing.Options{})
+
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	r := &runner{tracer: tracer, locker: lostLocker{}}
+	cfg := config.Config{StateLocation: "state", SyncMode: "normal", MaxRetries: 3}
+	err := r.runPipeline(testContext(), cfg, "a.yaml", "job")
+	if got := statusFromErr(err); got != "lock_lost" {
+		t.Fatalf("status = %q (%v), want lock_lost", got, err)
+	}
+	if got := spanStatuses(sr)["a.yaml"]; got != "lock_lost" {
+		t.Fatalf("span status = %q, want lock_lost", got)
+	}
+}
+
+func TestParseLockDSN(t *testing.T) {
+	tests := []struct {
+		dsn        string
+		wantDriver string
+		wantSource string
+		wantErr    bool
+	}{
+		{"postgres://sync@db:5432/locks?sslmode=disable", "postgres", "postgres://sync@db:5432/locks?sslmode=disable", false},
+		{"postgresql://db/locks", "postgres", "postgresql://db/locks", false},
+		{"mysql://db/locks", "", "", true},
+		{"", "", "", true},
+	}
+	for _, tt := range tests {
+		driver, source, err := parseLockDSN(tt.dsn)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLockDSN(%q) error = %v, wantErr %v", tt.dsn, err, tt.wantErr)
+			continue
+		}
+		if driver != tt.wantDriver || source != tt.wantSource {
+			t.Errorf("parseLockDSN(%q) = %q, %q, want %q, %q", tt.dsn, driver, source, tt.wantDriver, tt.wantSource)
+		}
+	}
+
+	driver, source, err := parseLockDSN("sqlite3:///var/lib/sync/locks.db")
+	switch {
+	case !sqliteLocks && err == nil:
+		t.Errorf("sqlite3 lock DSN accepted without cgo")
+	case sqliteLocks && (err != nil || driver != "sqlite3" || source != "/var/lib/sync/locks.db"):
+		t.Errorf("parseLockDSN(sqlite3) = %q, %q, %v", driver, source, err)
+	}
+}
@@ -1,13 +1,70 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 )
 
+// Exit codes. They are part of the wrapper's interface and documented in the
+// README.
+const (
+	exitOK = 0
+	// exitFailure is used for errors that fit no other category.
+	exitFailure = 1
+	// exitConfigError reports invalid flags, pipelines or rule files.
+	exitConfigError = 2
+	// exitPartialFailure reports that some pipelines succeeded and others
+	// did not.
+	exitPartialFailure = 3
+	// exitAllFailed reports that no pipeline succeeded.
+	exitAllFailed = 4
+	// exitDeadlineExceeded reports that pipelines were deferred or
+	// interrupted because the run deadline was reached.
+	exitDeadlineExceeded = 5
+	// exitCancelled reports that the run was interrupted by a signal.
+	exitCancelled = 6
+	// exitLockHeld reports that another invocation holds the run lock.
+	exitLockHeld = 7
+	// exitLockLost reports that a pipeline was stopped because its lock
+	// lease could not be kept.
+	exitLockLost = 8
+)
+
 func main() {
-	if err := Execute(); err != nil {
+	err := Execute()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
 	}
+	os.Exit(exitCode(err))
+}
+
+// exitCode maps the error returned by a command to the process exit code.
+// Interruptions take precedence over pipeline failures because they explain
+// why pipelines did not succeed.
+func exitCode(err error) int {
+	var cfgErr *configError
+	var failed *pipelinesFailedError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &cfgErr):
+		return exitConfigError
+	case errors.Is(err, errLockHeld):
+		return exitLockHeld
+	case errors.Is(err, errLockLost):
+		// Checked before cancellation, which the lost lease caused.
+		return exitLockLost
+	case errors.Is(err, errRunDeadline):
+		return exitDeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return exitCancelled
+	case errors.As(err, &failed):
+		if failed.failed == failed.total {
+			return exitAllFailed
+		}
+		return exitPartialFailure
+	}
+	return exitFailure
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/config"
+)
+
+func TestExitCodes(t *testing.T) {
+	stubTracing(t)
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	failing := func(names ...string) func(context.Context, slingRun, trace.Span) (int, error) {
+		return func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+			for _, name := range names {
+				if filepath.Base(run.Pipeline) == name {
+					return 0, fmt.Errorf("boom")
+				}
+			}
+			return 0, nil
+		}
+	}
+	baseCfg := func(dir string) config.Config {
+		return config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", DataDir: t.TempDir(), SyncMode: "normal",
+			MaxRetries: 1, BackoffBase: time.Millisecond, Concurrency: 1}
+	}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T) error
+		want int
+	}{
+		{"success", func(t *testing.T) error {
+			runSlingOnceFunc = failing()
+			return run(testContext(), baseCfg(writePipelines(t, "a.yaml")))
+		}, exitOK},
+		{"no pipelines", func(t *testing.T) error {
+			return run(testContext(), baseCfg(t.TempDir()))
+		}, exitConfigError},
+		{"dependency cycle", func(t *testing.T) error {
+			dir := writePipelines(t, "a.yaml")
+			os.WriteFile(filepath.Join(dir, "a.wrapper.yaml"), []byte("depends_on: [a]\n"), 0644)
+			return run(testContext(), baseCfg(dir))
+		}, exitConfigError},
+		{"invalid flag", func(t *testing.T) error {
+			cmd := newRootCmd()
+			cmd.SetArgs([]string{"noop", "--concurrency", "many"})
+			cmd.SetErr(io.Discard)
+			return cmd.Execute()
+		}, exitConfigError},
+		{"partial failure", func(t *testing.T) error {
+			runSlingOnceFunc = failing("a.yaml")
+			return run(testContext(), baseCfg(writePipelines(t, "a.yaml", "b.yaml")))
+		}, exitPartialFailure},
+		{"all failed", func(t *testing.T) error {
+			runSlingOnceFunc = failing("a.yaml", "b.yaml")
+			return run(testContext(), baseCfg(writePipelines(t, "a.yaml", "b.yaml")))
+		}, exitAllFailed},
+		{"deadline exceeded", func(t *testing.T) error {
+			runSlingOnceFunc = failing()
+			dir := writePipelines(t, "a.yaml", "b.yaml")
+			os.WriteFile(filepath.Join(dir, "b.wrapper.yaml"), []byte("expected_duration: 2h\n"), 0644)
+			cfg := baseCfg(dir)
+			cfg.RunDeadline = time.Hour
+			return run(testContext(), cfg)
+		}, exitDeadlineExceeded},
+		{"cancelled", func(t *testing.T) error {
+			ctx, cancel := context.WithCancel(testContext())
+			defer cancel()
+			runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+				cancel()
+				return 0, fmt.Errorf("command context: %w", ctx.Err())
+			}
+			return run(ctx, baseCfg(writePipelines(t, "a.yaml", "b.yaml")))
+		}, exitCancelled},
+		{"lock held", func(t *testing.T) error {
+			return fmt.Errorf("acquire run lock: %w", errLockHeld)
+		}, exitLockHeld},
+		{"lock lost", func(t *testing.T) error {
+			lost := fmt.Errorf("%w: %w", errLockLost, fmt.Errorf("command context: %w", context.Canceled))
+			return &pipelinesFailedError{failed: 1, total: 1, err: fmt.Errorf("sling run failed: %w", lost)}
+		}, exitLockLost},
+		{"other", func(t *testing.T) error {
+			return errors.New("unexpected")
+		}, exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run(t)
+			if got := exitCode(err); got != tt.want {
+				t.Fatalf("exitCode(%v) = %d, want %d", err, got, tt.want)
+			}
+		})
+	}
+}
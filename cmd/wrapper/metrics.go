@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"sling-sync-wrapper/internal/breaker"
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/logging"
+	"sling-sync-wrapper/internal/metrics"
+	"sling-sync-wrapper/internal/tracing"
+)
+
+// pushTimeout bounds how long pushing metrics may delay exit.
+const pushTimeout = 10 * time.Second
+
+// newMetrics returns the Prometheus metrics of the process. The time since
+// the last success of each pipeline starts from the health store so that it
+// spans runs.
+func newMetrics(cfg config.Config, health *breaker.Store) *metrics.Metrics {
+	m := metrics.New(cfg.MissionClusterID)
+	if health != nil {
+		for name, rec := range health.Records() {
+			if !rec.LastSuccess.IsZero() {
+				m.SetLastSuccess(name, rec.LastSuccess)
+			}
+		}
+	}
+	return m
+}
+
+// pushMetrics pushes m to the configured Pushgateway. Failures are only
+// logged so that they do not mask the result of the run.
+func pushMetrics(ctx context.Context, cfg config.Config, m *metrics.Metrics) {
+	if cfg.PushgatewayURL == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pushTimeout)
+	defer cancel()
+	if err := m.Push(ctx, cfg.PushgatewayURL); err != nil {
+		logging.FromContext(ctx).Error("failed to push metrics", "url", cfg.PushgatewayURL, "err", err)
+	}
+}
+
+// newInstruments creates the OpenTelemetry instruments on the meter provider
+// installed by tracing.Init. Without them runs only go unmeasured, so errors
+// are logged rather than returned.
+func newInstruments(ctx context.Context, cfg config.Config) *tracing.Instruments {
+	inst, err := tracing.NewInstruments(meterProviderFunc(), cfg.MissionClusterID)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to create metric instruments", "err", err)
+		return nil
+	}
+	return inst
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/config"
+)
+
+func TestRunPushesMetrics(t *testing.T) {
+	stubTracing(t)
+	var mu sync.Mutex
+	var paths []string
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	dir := writePipelines(t, "a.yaml", "b.yaml")
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		if strings.HasSuffix(run.Pipeline, "b.yaml") {
+			return 0, fmt.Errorf("boom")
+		}
+		return 5, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", SyncMode: "normal",
+		MaxRetries: 1, Concurrency: 1, DataDir: t.TempDir(), PushgatewayURL: srv.URL}
+	if err := run(testContext(), cfg); err == nil {
+		t.Fatal("expected error for failed pipeline")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || paths[0] != "/metrics/job/sling-sync-wrapper/mission_cluster_id/mc" {
+		t.Fatalf("pushed to %v, want once to the mission cluster group", paths)
+	}
+	for _, name := range []string{"sling_sync_runs_total", "sling_sync_attempts_total", "sling_sync_run_duration_seconds", "sling_sync_rows_synced"} {
+		if !strings.Contains(body, name) {
+			t.Errorf("pushed metrics lack %s", name)
+		}
+	}
+}
+
+func TestRunRecordsOTelMetrics(t *testing.T) {
+	stubTracing(t)
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	meterProviderFunc = func() metric.MeterProvider { return mp }
+	defer func() { meterProviderFunc = otel.GetMeterProvider }()
+
+	dir := writePipelines(t, "a.yaml", "b.yaml")
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		if strings.HasSuffix(run.Pipeline, "b.yaml") {
+			run.Instruments.RecordParseFailure(ctx, config.PipelineName(run.Pipeline))
+			return 0, fmt.Errorf("boom")
+		}
+		return 5, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+	sleepFunc = func(ctx context.Context, d time.Duration) error { return nil }
+	defer func() { sleepFunc = sleepContext }()
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", SyncMode: "normal",
+		MaxRetries: 2, Concurrency: 1, DataDir: t.TempDir()}
+	if err := run(testContext(), cfg); err == nil {
+		t.Fatal("expected error for failed pipeline")
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					pipeline, _ := dp.Attributes.Value("pipeline")
+					got[m.Name+"/"+pipeline.AsString()] += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					pipeline, _ := dp.Attributes.Value("pipeline")
+					got[m.Name+"/"+pipeline.AsString()] += int64(dp.Count)
+				}
+			}
+		}
+	}
+	want := map[string]int64{
+		"sling.sync.runs/a":               1,
+		"sling.sync.runs/b":               1,
+		"sling.sync.rows_synced/a":        5,
+		"sling.sync.retries/b":            1,
+		"sling.sync.sling.duration/a":     1,
+		"sling.sync.sling.duration/b":     2,
+		"sling.sync.log_parse_failures/b": 2,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %d, want %d (all: %v)", k, got[k], v, got)
+		}
+	}
+}
@@ -17,7 +17,7 @@ import (
 
 func TestRunPipelineNoop(t *testing.T) {
 	var called bool
-	runSlingOnceFunc = func(ctx context.Context, bin, pipeline, state, jobID string, span trace.Span) (int, error) {
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
 		called = true
 		return 0, nil
 	}
@@ -32,7 +32,7 @@ func TestRunPipelineNoop(t *testing.T) {
 	ctx := logging.NewContext(context.Background(), logger)
 
 	cfg := config.Config{MissionClusterID: "mc", StateLocation: "state", SyncMode: "noop", MaxRetries: 1, BackoffBase: time.Millisecond}
-	if err := runPipeline(ctx, tracer, cfg, "pipe.yaml", "job1"); err != nil {
+	if err := (&runner{tracer: tracer}).runPipeline(ctx, cfg, "pipe.yaml", "job1"); err != nil {
 		t.Fatalf("runPipeline returned error: %v", err)
 	}
 
@@ -59,7 +59,7 @@ func TestRunPipelineNoop(t *testing.T) {
 
 func TestRunPipelineBackfill(t *testing.T) {
 	var called bool
-	runSlingOnceFunc = func(ctx context.Context, bin, pipeline, state, jobID string, span trace.Span) (int, error) {
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
 		called = true
 		return 0, nil
 	}
@@ -70,7 +70,7 @@ func TestRunPipelineBackfill(t *testing.T) {
 	tracer := tp.Tracer("test")
 
 	cfg := config.Config{MissionClusterID: "mc", StateLocation: "state", SyncMode: "backfill", MaxRetries: 1, BackoffBase: time.Millisecond}
-	if err := runPipeline(testContext(), tracer, cfg, "pipe.yaml", "job1"); err != nil {
+	if err := (&runner{tracer: tracer}).runPipeline(testContext(), cfg, "pipe.yaml", "job1"); err != nil {
 		t.Fatalf("runPipeline returned error: %v", err)
 	}
 
@@ -94,14 +94,14 @@ func TestRunPipelineBackfill(t *testing.T) {
 }
 
 func TestRunPipelineReturnsError(t *testing.T) {
-	runSlingOnceFunc = func(ctx context.Context, bin, pipeline, state, jobID string, span trace.Span) (int, error) {
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
 		return 0, fmt.Errorf("boom")
 	}
 	defer func() { runSlingOnceFunc = runSlingOnce }()
 
 	tracer := trace.NewNoopTracerProvider().Tracer("test")
 	cfg := config.Config{MissionClusterID: "mc", StateLocation: "state", SyncMode: "normal", MaxRetries: 2, BackoffBase: time.Millisecond}
-	if err := runPipeline(testContext(), tracer, cfg, "pipe.yaml", "job1"); err == nil {
+	if err := (&runner{tracer: tracer}).runPipeline(testContext(), cfg, "pipe.yaml", "job1"); err == nil {
 		t.Fatalf("expected error from runPipeline")
 	}
 }
@@ -15,13 +15,13 @@ func TestProcessLogLineValid(t *testing.T) {
 
 	ctx := context.Background()
 	ctx, span := tracer.Start(ctx, "run")
-	rows, err := processLogLine(`{"level":"info","message":"rows","rows":5}`, span)
+	entry, err := processLogLine(`{"level":"info","message":"rows","rows":5}`, span)
 	span.End()
 	if err != nil {
 		t.Fatalf("processLogLine error: %v", err)
 	}
-	if rows != 5 {
-		t.Fatalf("expected 5 rows, got %d", rows)
+	if entry.Rows != 5 {
+		t.Fatalf("expected 5 rows, got %d", entry.Rows)
 	}
 
 	ended := sr.Ended()
@@ -70,13 +70,16 @@ func TestProcessLogLineWithErrorField(t *testing.T) {
 
 	ctx := context.Background()
 	ctx, span := tracer.Start(ctx, "run")
-	rows, err := processLogLine(`{"level":"error","message":"fail","error":"boom"}`, span)
+	entry, err := processLogLine(`{"level":"error","message":"fail","error":"boom"}`, span)
 	span.End()
 	if err != nil {
 		t.Fatalf("processLogLine error: %v", err)
 	}
-	if rows != 0 {
-		t.Fatalf("expected 0 rows, got %d", rows)
+	if entry.Rows != 0 {
+		t.Fatalf("expected 0 rows, got %d", entry.Rows)
+	}
+	if entry.Error != "boom" {
+		t.Fatalf("expected error field boom, got %q", entry.Error)
 	}
 
 	ended := sr.Ended()
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/logging"
+	"sling-sync-wrapper/internal/report"
+)
+
+// writeReports completes rep with the outcome of the run and writes the
+// configured report and termination message. Failures are only logged so
+// that they do not mask the result of the run.
+func writeReports(ctx context.Context, cfg config.Config, rep *report.Report, runErr error) {
+	rep.FinishedAt = time.Now()
+	rep.DurationSeconds = rep.FinishedAt.Sub(rep.StartedAt).Seconds()
+	rep.Status = "success"
+	if runErr != nil {
+		rep.Status = "failed"
+		rep.Error = runErr.Error()
+	}
+
+	logger := logging.FromContext(ctx)
+	if cfg.ReportFile != "" {
+		if err := report.WriteFile(cfg.ReportFile, cfg.ReportFormat, rep); err != nil {
+			logger.Error("failed to write run report", "path", cfg.ReportFile, "err", err)
+		}
+	}
+	if cfg.TerminationLog != "" {
+		if err := os.WriteFile(cfg.TerminationLog, []byte(rep.Summary()), 0644); err != nil {
+			logger.Error("failed to write termination log", "path", cfg.TerminationLog, "err", err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/report"
+)
+
+func TestRunWritesReport(t *testing.T) {
+	recordSpans(t)
+	dir := writePipelines(t, "dims.yaml", "facts.yaml", "lookups.yaml")
+	os.WriteFile(filepath.Join(dir, "facts.wrapper.yaml"), []byte("depends_on: [dims]\n"), 0644)
+
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		if filepath.Base(run.Pipeline) == "dims.yaml" {
+			return 0, fmt.Errorf("connection refused")
+		}
+		return 42, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	out := t.TempDir()
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", DataDir: t.TempDir(), SyncMode: "normal",
+		MaxRetries: 2, BackoffBase: time.Millisecond, Concurrency: 1,
+		ReportFile: filepath.Join(out, "report.json"), ReportFormat: report.FormatJSON,
+		TerminationLog: filepath.Join(out, "termination-log")}
+	if err := run(testContext(), cfg); err == nil {
+		t.Fatalf("expected error from run")
+	}
+
+	data, err := os.ReadFile(cfg.ReportFile)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var rep report.Report
+	if err := json.Unmarshal(data, &rep); err != nil {
+		t.Fatalf("parse report: %v", err)
+	}
+	if rep.Status != "failed" || len(rep.Pipelines) != 3 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	byName := map[string]report.Pipeline{}
+	for _, p := range rep.Pipelines {
+		if p.JobID == "" || p.TraceID == "" {
+			t.Errorf("pipeline %s lacks job or trace ID: %+v", p.Name, p)
+		}
+		byName[p.Name] = p
+	}
+	if p := byName["dims"]; p.Status != "failed" || p.Attempts != 2 || p.ErrorClass != "transient" {
+		t.Errorf("unexpected dims result: %+v", p)
+	}
+	if p := byName["facts"]; p.Status != "skipped" || p.Attempts != 0 {
+		t.Errorf("unexpected facts result: %+v", p)
+	}
+	if p := byName["lookups"]; p.Status != "success" || p.RowsSynced != 42 || p.Attempts != 1 {
+		t.Errorf("unexpected lookups result: %+v", p)
+	}
+
+	summary, _ := os.ReadFile(cfg.TerminationLog)
+	if !strings.Contains(string(summary), "dims: failed [transient]: connection refused") {
+		t.Fatalf("unexpected termination log:\n%s", summary)
+	}
+}
+
+func TestRunWritesTerminationLogOnConfigError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "termination-log")
+	cfg := config.Config{PipelineDir: t.TempDir(), SyncMode: "normal", TerminationLog: path}
+	if err := run(testContext(), cfg); err == nil {
+		t.Fatalf("expected error without pipelines")
+	}
+	summary, _ := os.ReadFile(path)
+	if !strings.Contains(string(summary), "no pipeline files found") {
+		t.Fatalf("unexpected termination log:\n%s", summary)
+	}
+}
+
+func TestRunRejectsUnknownReportFormat(t *testing.T) {
+	cfg := config.Config{PipelineDir: writePipelines(t, "a.yaml"), SyncMode: "noop",
+		ReportFile: filepath.Join(t.TempDir(), "report"), ReportFormat: "yaml"}
+	if err := run(testContext(), cfg); err == nil || !strings.Contains(err.Error(), "report format") {
+		t.Fatalf("expected report format error, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/checkpoint"
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/logging"
+)
+
+// checkpointFile is the name of the run checkpoint in the data dir.
+const checkpointFile = "sling_checkpoint.json"
+
+func checkpointPath(cfg config.Config) string {
+	return filepath.Join(config.DataDir(cfg), checkpointFile)
+}
+
+// resume loads the checkpoint of the previous run and returns it together
+// with the pipelines that did not succeed in it and the spans of their
+// previous attempts keyed by name. Dependencies on pipelines that already
+// succeeded are dropped. Without a checkpoint every pipeline is run.
+func resume(ctx context.Context, cfg config.Config, start time.Time, pipelines []config.Pipeline) (*checkpoint.Checkpoint, []config.Pipeline, map[string]trace.SpanContext, error) {
+	logger := logging.FromContext(ctx)
+	path := checkpointPath(cfg)
+	prev, err := checkpoint.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		logger.Warn("no checkpoint to resume from, running all pipelines", "path", path)
+		return checkpoint.New(path, start), pipelines, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("load checkpoint: %w", err)
+	}
+
+	done := map[string]bool{}
+	var remaining []config.Pipeline
+	links := map[string]trace.SpanContext{}
+	for _, p := range pipelines {
+		e, ok := prev.Entry(p.Name)
+		if ok && e.Succeeded() {
+			done[p.Name] = true
+			continue
+		}
+		if sc, ok := spanContextFromEntry(e); ok {
+			links[p.Name] = sc
+		}
+		remaining = append(remaining, p)
+	}
+	for i, p := range remaining {
+		p.Options.DependsOn = slices.DeleteFunc(slices.Clone(p.Options.DependsOn), func(dep string) bool { return done[dep] })
+		remaining[i] = p
+	}
+
+	logger.Info("resuming previous run", "checkpoint_started_at", prev.StartedAt(),
+		"pipelines", len(remaining), "already_succeeded", len(done))
+	return prev, remaining, links, nil
+}
+
+func spanContextFromEntry(e checkpoint.Entry) (trace.SpanContext, bool) {
+	traceID, err := trace.TraceIDFromHex(e.TraceID)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	spanID, err := trace.SpanIDFromHex(e.SpanID)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}), true
+}
+
+// spanOptions links the span of a resumed pipeline to the span of its
+// previous attempt.
+func (r *runner) spanOptions(pipeline string) []trace.SpanStartOption {
+	sc, ok := r.resumedFrom[pipeline]
+	if !ok {
+		return nil
+	}
+	return []trace.SpanStartOption{trace.WithLinks(trace.Link{
+		SpanContext: sc,
+		Attributes:  []attribute.KeyValue{attribute.String("link.type", "resumed_from")},
+	})}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/config"
+)
+
+func TestRunResume(t *testing.T) {
+	sr := recordSpans(t)
+	dir := writePipelines(t, "dims.yaml", "facts.yaml", "summary.yaml", "lookups.yaml")
+	os.WriteFile(filepath.Join(dir, "facts.wrapper.yaml"), []byte("depends_on: [dims]\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "summary.wrapper.yaml"), []byte("depends_on: [facts, lookups]\n"), 0644)
+
+	var mu sync.Mutex
+	var ran []string
+	failFacts := true
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		name := config.PipelineName(run.Pipeline)
+		ran = append(ran, name)
+		if name == "facts" && failFacts {
+			return 0, fmt.Errorf("boom")
+		}
+		return 0, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", DataDir: t.TempDir(),
+		SyncMode: "normal", MaxRetries: 1, Concurrency: 1}
+	if err := run(testContext(), cfg); err == nil {
+		t.Fatalf("expected first run to fail")
+	}
+	var factsSpan trace.SpanContext
+	for _, s := range sr.Ended() {
+		for _, kv := range s.Attributes() {
+			if kv.Key == "pipeline" && config.PipelineName(kv.Value.AsString()) == "facts" {
+				factsSpan = s.SpanContext()
+			}
+		}
+	}
+
+	ran = nil
+	failFacts = false
+	cfg.Resume = true
+	if err := run(testContext(), cfg); err != nil {
+		t.Fatalf("resumed run failed: %v", err)
+	}
+	sort.Strings(ran)
+	if fmt.Sprint(ran) != "[facts summary]" {
+		t.Fatalf("resumed run ran %v, want [facts summary]", ran)
+	}
+
+	ended := pipelineSpans(sr)
+	var linked bool
+	for _, s := range ended[len(ended)-2:] {
+		for _, l := range s.Links() {
+			linked = linked || (l.SpanContext.TraceID() == factsSpan.TraceID() && l.SpanContext.SpanID() == factsSpan.SpanID())
+		}
+	}
+	if !linked {
+		t.Fatalf("resumed facts span is not linked to the original run")
+	}
+
+	ran = nil
+	if err := run(testContext(), cfg); err != nil {
+		t.Fatalf("second resume failed: %v", err)
+	}
+	if len(ran) != 0 {
+		t.Fatalf("nothing should be left to resume, ran %v", ran)
+	}
+}
+
+func TestRunResumeWithoutCheckpoint(t *testing.T) {
+	stubTracing(t)
+	dir := writePipelines(t, "a.yaml", "b.yaml")
+	calls := 0
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		calls++
+		return 0, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", DataDir: t.TempDir(),
+		SyncMode: "normal", MaxRetries: 1, BackoffBase: time.Millisecond, Concurrency: 1, Resume: true}
+	if err := run(testContext(), cfg); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected all pipelines to run without checkpoint, got %d", calls)
+	}
+}
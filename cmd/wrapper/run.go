@@ -2,54 +2,364 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
+	"sling-sync-wrapper/internal/breaker"
+	"sling-sync-wrapper/internal/checkpoint"
 	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/lock"
 	"sling-sync-wrapper/internal/logging"
+	"sling-sync-wrapper/internal/metrics"
+	"sling-sync-wrapper/internal/report"
+	"sling-sync-wrapper/internal/retry"
 	"sling-sync-wrapper/internal/tracing"
 
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// telemetryShutdownTimeout bounds how long flushing telemetry may delay exit.
+const telemetryShutdownTimeout = 10 * time.Second
+
 var (
-	runSlingOnceFunc = runSlingOnce
-	sleepFunc        = time.Sleep
-	removeAllFunc    = os.RemoveAll
+	runSlingOnceFunc  = runSlingOnce
+	sleepFunc         = sleepContext
+	removeAllFunc     = os.RemoveAll
+	tracingInitFunc   = tracing.Init
+	meterProviderFunc = otel.GetMeterProvider
 )
 
 // run executes all configured pipelines according to cfg.
-func run(ctx context.Context, cfg config.Config) error {
-	pipelines, err := config.Pipelines(cfg)
+func run(ctx context.Context, cfg config.Config) (err error) {
+	start := time.Now()
+	rep := &report.Report{MissionClusterID: cfg.MissionClusterID, SyncMode: cfg.SyncMode, StartedAt: start}
+	defer func() { writeReports(ctx, cfg, rep, err) }()
+
+	if cfg.ReportFile != "" {
+		if err := report.ValidateFormat(cfg.ReportFormat); err != nil {
+			return &configError{fmt.Errorf("invalid report format: %w", err)}
+		}
+	}
+
+	pipelines, err := config.LoadPipelines(cfg)
+	if err != nil {
+		return &configError{fmt.Errorf("load pipelines: %w", err)}
+	}
+	graph, err := buildGraph(pipelines)
+	if err != nil {
+		return &configError{fmt.Errorf("load pipelines: %w", err)}
+	}
+
+	var ckpt *checkpoint.Checkpoint
+	var resumedFrom map[string]trace.SpanContext
+	if cfg.SyncMode == "normal" {
+		ckpt = checkpoint.New(checkpointPath(cfg), start)
+		if cfg.Resume {
+			if ckpt, pipelines, resumedFrom, err = resume(ctx, cfg, start, pipelines); err != nil {
+				return &configError{err}
+			}
+			if len(pipelines) == 0 {
+				logging.FromContext(ctx).Info("nothing to resume, all pipelines succeeded")
+				return nil
+			}
+			// The full graph was validated above; this one only drops
+			// the pipelines that already succeeded.
+			if graph, err = buildGraph(pipelines); err != nil {
+				return &configError{fmt.Errorf("load pipelines: %w", err)}
+			}
+		}
+	}
+
+	classifier, err := loadErrorClassifier(cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := retry.ParseJitter(cfg.BackoffJitter); err != nil {
+		return &configError{fmt.Errorf("invalid backoff jitter: %w", err)}
+	}
+	// The invocation span continues the trace of whoever started the run. A
+	// bad header must not block the sync, so the run starts a new trace.
+	if ctx, err = tracing.ContextWithParent(ctx, cfg.TraceParent, cfg.TraceState); err != nil {
+		logging.FromContext(ctx).Warn("ignoring inbound trace context, starting a new trace", "err", err)
+	}
+
+	tracer, shutdown, degraded, err := initTracing(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	rep.TelemetryDegraded = degraded
+	defer func() {
+		// ctx may already be cancelled by a signal; spans of the interrupted
+		// run must still be flushed.
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), telemetryShutdownTimeout)
+		defer cancel()
+		if err := shutdown(shutdownCtx); err != nil {
+			logging.FromContext(ctx).Error("failed to shut down tracer", "err", err)
+		}
+	}()
+
+	locker, closeLocker, err := openLocker(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeLocker()
+
+	health := openHealthStore(ctx, cfg)
+	m := newMetrics(cfg, health)
+	defer pushMetrics(ctx, cfg, m)
+
+	r := &runner{
+		tracer:      tracer,
+		classifier:  classifier,
+		retryBudget: retry.NewBudget(cfg.RunRetryBudget),
+		health:      health,
+		metrics:     m,
+		instruments: newInstruments(ctx, cfg),
+		locker:      locker,
+		checkpoint:  ckpt,
+		resumedFrom: resumedFrom,
+	}
+	return r.execute(ctx, cfg, graph, start, rep)
+}
+
+// loadErrorClassifier builds the error classifier from the configured rules.
+func loadErrorClassifier(cfg config.Config) (*errorClassifier, error) {
+	var rules []config.ErrorRule
+	if cfg.ErrorRulesFile != "" {
+		var err error
+		if rules, err = config.LoadErrorRules(cfg.ErrorRulesFile); err != nil {
+			return nil, &configError{fmt.Errorf("load error rules: %w", err)}
+		}
+	}
+	classifier, err := newErrorClassifier(rules)
 	if err != nil {
-		return fmt.Errorf("load pipelines: %w", err)
+		return nil, &configError{fmt.Errorf("load error rules: %w", err)}
+	}
+	return classifier, nil
+}
+
+// execute runs the pipelines of graph, which started at start, and adds
+// their outcomes to rep.
+func (r *runner) execute(ctx context.Context, cfg config.Config, graph *pipelineGraph, start time.Time, rep *report.Report) error {
+	if cfg.RunDeadline > 0 {
+		r.deadline = start.Add(cfg.RunDeadline)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadlineCause(ctx, r.deadline, &deferredError{})
+		defer cancel()
 	}
 
-	tracer, shutdown := tracing.Init(ctx, "sling-sync-wrapper", cfg.MissionClusterID, cfg.OTELEndpoint)
-	defer shutdown(ctx)
+	// One span covers the whole invocation so that its pipelines share a
+	// trace.
+	ctx, span := r.tracer.Start(ctx, "sling.sync.invocation", trace.WithAttributes(
+		attribute.String("mission_cluster_id", cfg.MissionClusterID),
+		attribute.String("sync_mode", cfg.SyncMode),
+		attribute.Int("pipelines", len(graph.nodes)),
+	))
+	defer span.End()
+	if sc := span.SpanContext(); sc.IsValid() {
+		rep.TraceID = sc.TraceID().String()
+	}
 
-	var failed bool
-	for _, pipeline := range pipelines {
-		jobID := uuid.NewString()
-		if err := runPipeline(ctx, tracer, cfg, pipeline, jobID); err != nil {
-			failed = true
+	// Each pipeline gets its own job ID, logger and span inside runPipeline;
+	// executeGraph only collects the per-pipeline errors.
+	errs := executeGraph(ctx, graph, cfg.Concurrency,
+		func(ctx context.Context, n *pipelineNode) error {
+			cfg := n.pipeline.Options.Apply(cfg)
+			jobID := uuid.NewString()
+			if err := r.checkDeadline(n.pipeline); err != nil {
+				r.recordNotRun(ctx, cfg, n.pipeline, err, jobID)
+				return err
+			}
+			if !r.board.begin(n.pipeline.Name, activeRun{JobID: jobID, SyncMode: cfg.SyncMode, Trigger: triggerSchedule, StartedAt: time.Now()}) {
+				err := fmt.Errorf("%w: %s is already running in this process", errLockHeld, n.pipeline.Name)
+				r.recordNotRun(ctx, cfg, n.pipeline, err, jobID)
+				return err
+			}
+			return r.runPipeline(ctx, cfg, n.pipeline.Path, jobID)
+		},
+		func(ctx context.Context, n *pipelineNode, reason error) {
+			r.recordNotRun(ctx, cfg, n.pipeline, reason, uuid.NewString())
+		},
+	)
+	r.buildReport(graph, rep)
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			errs[i] = fmt.Errorf("%s: %w", graph.nodes[i].pipeline.Path, err)
+			failed++
 		}
 	}
-	if failed {
-		return fmt.Errorf("one or more pipelines failed")
+
+	span.SetAttributes(attribute.Int("pipelines_failed", failed))
+	if failed > 0 {
+		err := &pipelinesFailedError{failed: failed, total: len(errs), err: errors.Join(errs...)}
+		span.SetAttributes(attribute.String("status", "failed"))
+		span.RecordError(err)
+		return err
 	}
+	span.SetAttributes(attribute.String("status", "success"))
 	return nil
 }
 
-func runPipeline(ctx context.Context, tracer trace.Tracer, cfg config.Config, pipeline, jobID string) error {
+// configError reports an invalid configuration detected before any pipeline
+// was started.
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string { return e.err.Error() }
+
+func (e *configError) Unwrap() error { return e.err }
+
+// pipelinesFailedError reports that some or all pipelines of a run did not
+// succeed. err joins the errors of the individual pipelines.
+type pipelinesFailedError struct {
+	failed int
+	total  int
+	err    error
+}
+
+func (e *pipelinesFailedError) Error() string {
+	return fmt.Sprintf("%d of %d pipelines failed: %v", e.failed, e.total, e.err)
+}
+
+func (e *pipelinesFailedError) Unwrap() error { return e.err }
+
+// runner holds the state shared by the pipelines of a single invocation.
+type runner struct {
+	tracer     trace.Tracer
+	classifier *errorClassifier
+	// retryBudget limits the retry time of all pipelines together.
+	retryBudget *retry.Budget
+	// health tracks circuit breakers and run durations. It is nil when
+	// neither circuit breaking nor a run deadline is configured.
+	health *breaker.Store
+	// deadline is the end of the run deadline, or zero if there is none.
+	deadline time.Time
+	// metrics counts runs and attempts for Prometheus.
+	metrics *metrics.Metrics
+	// instruments record the same for OpenTelemetry.
+	instruments *tracing.Instruments
+	// board shows the pipelines' runs to the HTTP server of a daemon. It is
+	// nil otherwise.
+	board *statusBoard
+	// locker keeps two invocations from syncing the same pipeline against
+	// the same state at once. It is nil when locking is disabled.
+	locker lock.Locker
+
+	// checkpoint records pipeline outcomes for run --resume. It is nil
+	// outside of normal runs.
+	checkpoint *checkpoint.Checkpoint
+	// resumedFrom holds the spans of the previous attempt of each pipeline
+	// keyed by name when resuming.
+	resumedFrom map[string]trace.SpanContext
+
+	mu sync.Mutex
+	// results holds the outcome of each pipeline keyed by path.
+	results map[string]*report.Pipeline
+}
+
+// newResult returns the report entry for a pipeline run traced by span.
+func (r *runner) newResult(pipeline, jobID string, span trace.Span) *report.Pipeline {
+	res := &report.Pipeline{Name: config.PipelineName(pipeline), Path: pipeline, JobID: jobID}
+	if sc := span.SpanContext(); sc.IsValid() {
+		res.TraceID = sc.TraceID().String()
+		res.SpanID = sc.SpanID().String()
+	}
+	return res
+}
+
+// addResult stores the outcome of a pipeline for the report and records it
+// in the checkpoint.
+func (r *runner) addResult(ctx context.Context, res *report.Pipeline) {
+	r.mu.Lock()
+	if r.results == nil {
+		r.results = map[string]*report.Pipeline{}
+	}
+	r.results[res.Path] = res
+	r.mu.Unlock()
+	r.board.finish(res)
+	r.metrics.ObserveRun(res.Name, res.Status, res.Attempts,
+		time.Duration(res.DurationSeconds*float64(time.Second)), res.RowsSynced)
+	r.instruments.RecordRun(ctx, res.Name, res.Status, res.RowsSynced)
+
+	if r.checkpoint == nil {
+		return
+	}
+	err := r.checkpoint.Record(res.Name, checkpoint.Entry{
+		Status:     res.Status,
+		JobID:      res.JobID,
+		TraceID:    res.TraceID,
+		SpanID:     res.SpanID,
+		FinishedAt: time.Now(),
+		Error:      res.Error,
+	})
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to write checkpoint", "err", err)
+	}
+}
+
+// buildReport collects the pipeline results in graph order.
+func (r *runner) buildReport(g *pipelineGraph, rep *report.Report) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, n := range g.nodes {
+		if res := r.results[n.pipeline.Path]; res != nil {
+			rep.Pipelines = append(rep.Pipelines, *res)
+		}
+	}
+}
+
+// recordNotRun emits the span and log entry for a pipeline that was not run,
+// either because upstream failed or because the run was interrupted.
+func (r *runner) recordNotRun(ctx context.Context, cfg config.Config, pipeline config.Pipeline, reason error, jobID string) {
+	logger := logging.FromContext(ctx).With("pipeline", pipeline.Path, "sync_job_id", jobID)
+	ctx, span := r.tracer.Start(ctx, "sling.sync.run", r.spanOptions(pipeline.Name)...)
+	defer span.End()
+
+	status := statusFromErr(reason)
+	res := r.newResult(pipeline.Path, jobID, span)
+	res.Status = status
+	res.Error = reason.Error()
+	r.addResult(ctx, res)
+	span.SetAttributes(
+		attribute.String("mission_cluster_id", cfg.MissionClusterID),
+		attribute.String("sync_job_id", jobID),
+		attribute.String("pipeline", pipeline.Path),
+		attribute.String("state_location", cfg.StateLocation),
+		attribute.String("sync_mode", cfg.SyncMode),
+		attribute.String("status", status),
+	)
+	var deferred *deferredError
+	if errors.As(reason, &deferred) && deferred.expected > 0 {
+		span.SetAttributes(
+			attribute.Float64("expected_duration_seconds", deferred.expected.Seconds()),
+			attribute.Float64("remaining_seconds", deferred.remaining.Seconds()),
+		)
+	}
+	var upstream *upstreamFailedError
+	if errors.As(reason, &upstream) {
+		span.SetAttributes(attribute.String("upstream_pipeline", upstream.upstream))
+		logger.WarnContext(ctx, "pipeline skipped", "status", status, "upstream_pipeline", upstream.upstream)
+		return
+	}
+	logger.WarnContext(ctx, "pipeline not started", "status", status, "reason", reason)
+}
+
+func (r *runner) runPipeline(ctx context.Context, cfg config.Config, pipeline, jobID string) error {
 	logger := logging.FromContext(ctx).With("pipeline", pipeline, "sync_job_id", jobID)
 	ctx = logging.NewContext(ctx, logger)
-	ctx, span := tracer.Start(ctx, "sling.sync.run")
+	ctx, span := r.tracer.Start(ctx, "sling.sync.run", r.spanOptions(config.PipelineName(pipeline))...)
 	defer span.End()
+	res := r.newResult(pipeline, jobID, span)
+	defer func() { r.addResult(ctx, res) }()
 
 	span.SetAttributes(
 		attribute.String("mission_cluster_id", cfg.MissionClusterID),
@@ -57,43 +367,137 @@ func runPipeline(ctx context.Context, tracer trace.Tracer, cfg config.Config, pi
 		attribute.String("pipeline", pipeline),
 		attribute.String("state_location", cfg.StateLocation),
 		attribute.String("sync_mode", cfg.SyncMode),
+		// Effective settings after per-pipeline overrides.
+		attribute.Float64("sling_timeout_seconds", cfg.SlingTimeout.Seconds()),
+		attribute.Int("max_retries", cfg.MaxRetries),
+		attribute.Float64("backoff_base_seconds", cfg.BackoffBase.Seconds()),
+		attribute.String("sling_binary", cfg.SlingBinary),
 	)
 
-	switch cfg.SyncMode {
-	case "noop":
-		logger.Info("would run Sling pipeline", "mode", "noop")
+	if cfg.SyncMode == "noop" {
+		logger.InfoContext(ctx, "would run Sling pipeline", "mode", "noop", "sling_binary", cfg.SlingBinary,
+			"sling_timeout", cfg.SlingTimeout, "max_retries", cfg.MaxRetries, "backoff_base", cfg.BackoffBase)
 		span.SetAttributes(attribute.String("status", "noop"))
+		res.Status = "noop"
 		return nil
-	case "backfill":
+	}
+
+	name := config.PipelineName(pipeline)
+	ctx, release, err := r.acquireLock(ctx, cfg, name, span)
+	if err != nil {
+		status := statusFromErr(err)
+		logger.WarnContext(ctx, "run lock unavailable, skipping pipeline", "status", status, "err", err)
+		span.RecordError(err)
+		span.SetAttributes(attribute.String("status", status))
+		res.Status, res.Error = status, err.Error()
+		return err
+	}
+	defer release()
+
+	if cfg.SyncMode == "backfill" {
 		if err := resetState(ctx, cfg); err != nil {
-			logger.Error("reset state failed", "err", err)
+			logger.ErrorContext(ctx, "reset state failed", "err", err)
 			span.RecordError(err)
 			span.SetAttributes(attribute.String("status", "failed"))
+			res.Status, res.Error = "failed", err.Error()
 			return fmt.Errorf("reset state: %w", err)
 		}
 		span.SetAttributes(attribute.String("status", "backfill"))
+		res.Status = "backfill"
 		return nil
 	}
 
+	if r.health != nil {
+		state, ok, err := r.health.Allow(name, time.Now())
+		if err != nil {
+			logger.WarnContext(ctx, "failed to update health store", "err", err)
+		}
+		span.SetAttributes(attribute.String("circuit.state", string(state)))
+		if !ok {
+			err := &circuitOpenError{pipeline: name}
+			logger.WarnContext(ctx, "circuit open, skipping pipeline", "status", statusFromErr(err))
+			span.SetAttributes(attribute.String("status", statusFromErr(err)))
+			res.Status, res.Error = statusFromErr(err), err.Error()
+			return err
+		}
+		if state == breaker.StateHalfOpen {
+			logger.InfoContext(ctx, "circuit half-open, running trial")
+			// Runs that record no result must not keep the trial slot.
+			defer r.health.Release(name)
+		}
+	}
+
 	startTime := time.Now()
 
-	prevTimeout := slingCLITimeout
-	slingCLITimeout = cfg.SlingTimeout
-	defer func() { slingCLITimeout = prevTimeout }()
+	jitter, err := retry.ParseJitter(cfg.BackoffJitter)
+	if err != nil {
+		res.Status, res.Error = "failed", err.Error()
+		return fmt.Errorf("invalid backoff jitter: %w", err)
+	}
+	policy := retry.Policy{Base: cfg.BackoffBase, Max: cfg.BackoffMax, Jitter: jitter}
+	pipelineBudget := retry.NewBudget(cfg.RetryBudget)
 
 	var lastErr error
+	var lastClass errorClass
 	var rowsSynced int
+	var wait time.Duration
 	for attempt := 1; attempt <= cfg.MaxRetries; attempt++ {
-		rows, err := runSlingOnceFunc(ctx, cfg.SlingBinary, pipeline, cfg.StateLocation, jobID, span)
+		attemptStart := time.Now()
+		res.Attempts = attempt
+		rows, class, err := r.runAttempt(ctx, cfg, pipeline, jobID, attempt)
+		r.metrics.ObserveAttempt(name, err)
+		r.instruments.RecordSling(ctx, name, time.Since(attemptStart), err)
 		rowsSynced += rows
+		if attempt > 1 {
+			// Retried attempts count against the budgets along with the
+			// wait that preceded them.
+			retried := time.Since(attemptStart) + wait
+			pipelineBudget.Consume(retried)
+			r.retryBudget.Consume(retried)
+		}
 		if err == nil {
 			lastErr = nil
 			break
 		}
 		lastErr = err
-		wait := cfg.BackoffBase * time.Duration(1<<uint(attempt-1))
-		logger.Error("attempt failed, retrying", "attempt", attempt, "err", err, "wait", wait)
-		sleepFunc(wait)
+		lastClass = class
+		span.AddEvent("attempt failed", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("error.class", string(lastClass)),
+		))
+		if ctx.Err() != nil || attempt == cfg.MaxRetries {
+			break
+		}
+		if lastClass == classPermanent {
+			logger.ErrorContext(ctx, "attempt failed, not retrying", "attempt", attempt, "err", err, "error_class", lastClass)
+			break
+		}
+		wait = policy.Backoff(attempt, wait)
+		if !pipelineBudget.Allows(wait) || !r.retryBudget.Allows(wait) {
+			logger.ErrorContext(ctx, "attempt failed, retry budget exhausted", "attempt", attempt, "err", err, "error_class", lastClass,
+				"pipeline_retry_seconds", pipelineBudget.Used().Seconds(), "run_retry_seconds", r.retryBudget.Used().Seconds())
+			span.AddEvent("retry budget exhausted", trace.WithAttributes(attribute.Int("attempt", attempt)))
+			break
+		}
+		logger.ErrorContext(ctx, "attempt failed, retrying", "attempt", attempt, "err", err, "error_class", lastClass, "wait", wait)
+		span.AddEvent("retry backoff", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.Float64("wait_seconds", wait.Seconds()),
+			attribute.String("jitter", string(jitter)),
+		))
+		r.instruments.RecordRetry(ctx, name, string(lastClass))
+		if err := r.backoff(ctx, attempt, wait, jitter); err != nil {
+			break
+		}
+	}
+
+	if lastErr != nil && ctx.Err() != nil {
+		switch cause := context.Cause(ctx); {
+		case errors.Is(cause, errRunDeadline):
+			lastErr = fmt.Errorf("%w: %w", errRunDeadline, lastErr)
+		case errors.Is(cause, errLockLost):
+			lastErr = fmt.Errorf("%w: %w", errLockLost, lastErr)
+		}
 	}
 
 	duration := time.Since(startTime)
@@ -101,17 +505,99 @@ func runPipeline(ctx context.Context, tracer trace.Tracer, cfg config.Config, pi
 		attribute.Int("rows_synced", rowsSynced),
 		attribute.Float64("duration_seconds", duration.Seconds()),
 	)
+	status := statusFromErr(lastErr)
 	if lastErr != nil {
 		span.RecordError(lastErr)
-		span.SetAttributes(attribute.String("status", "failed"))
-	} else {
-		span.SetAttributes(attribute.String("status", "success"))
+		span.SetAttributes(attribute.String("error.class", string(lastClass)))
+		logger = logger.With("error_class", lastClass)
+	}
+	span.SetAttributes(attribute.String("status", status))
+	res.Status = status
+	res.RowsSynced = rowsSynced
+	res.DurationSeconds = duration.Seconds()
+	if lastErr != nil {
+		res.ErrorClass = string(lastClass)
+		res.Error = lastErr.Error()
 	}
 
-	status := statusFromErr(lastErr)
-	logger.Info("pipeline completed", "duration_seconds", duration.Seconds(), "rows_synced", rowsSynced, "status", status)
+	logger.InfoContext(ctx, "pipeline completed", "duration_seconds", duration.Seconds(), "rows_synced", rowsSynced, "status", status)
+	r.recordHealth(ctx, name, lastErr, duration)
 	if lastErr != nil {
 		return fmt.Errorf("sling run failed: %w", lastErr)
 	}
 	return nil
 }
+
+// runAttempt runs one attempt of pipeline in its own sling.sync.attempt span,
+// which also receives the Sling log events. The error class is empty if the
+// attempt succeeded.
+func (r *runner) runAttempt(ctx context.Context, cfg config.Config, pipeline, jobID string, attempt int) (int, errorClass, error) {
+	ctx, span := r.tracer.Start(ctx, "sling.sync.attempt", trace.WithAttributes(attribute.Int("attempt", attempt)))
+	defer span.End()
+
+	rows, err := r.runSlingAttempt(ctx, cfg, pipeline, jobID, span)
+	span.SetAttributes(attribute.Int("rows_synced", rows))
+	var slingErr *slingError
+	switch {
+	case err == nil:
+		span.SetAttributes(attribute.Int("exit_code", 0), attribute.String("status", "success"))
+		return rows, "", nil
+	case errors.As(err, &slingErr):
+		span.SetAttributes(attribute.Int("exit_code", slingErr.ExitCode))
+	}
+	class := r.classifier.classify(err)
+	span.RecordError(err)
+	span.SetAttributes(
+		attribute.String("error.class", string(class)),
+		attribute.String("status", statusFromErr(err)),
+	)
+	return rows, class, err
+}
+
+// backoff waits before the retry of a failed attempt in a backoff span. It
+// returns an error if ctx is done first.
+func (r *runner) backoff(ctx context.Context, attempt int, wait time.Duration, jitter retry.Jitter) error {
+	ctx, span := r.tracer.Start(ctx, "backoff", trace.WithAttributes(
+		attribute.Int("attempt", attempt),
+		attribute.Float64("wait_seconds", wait.Seconds()),
+		attribute.String("jitter", string(jitter)),
+	))
+	defer span.End()
+	if err := sleepFunc(ctx, wait); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// runSlingAttempt runs Sling once, bounding the invocation by cfg.SlingTimeout.
+// The timeout is applied per call rather than through shared state so that
+// pipelines running in parallel cannot affect each other's limits.
+func (r *runner) runSlingAttempt(ctx context.Context, cfg config.Config, pipeline, jobID string, span trace.Span) (int, error) {
+	if cfg.SlingTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.SlingTimeout)
+		defer cancel()
+	}
+	return runSlingOnceFunc(ctx, slingRun{
+		Binary:           cfg.SlingBinary,
+		Pipeline:         pipeline,
+		StateLocation:    cfg.StateLocation,
+		JobID:            jobID,
+		MissionClusterID: cfg.MissionClusterID,
+		GracePeriod:      cfg.ShutdownGrace,
+		Instruments:      r.instruments,
+	}, span)
+}
+
+// sleepContext pauses for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
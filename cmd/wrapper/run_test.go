@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	lognoop "go.opentelemetry.io/otel/log/noop"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/logging"
+	"sling-sync-wrapper/internal/tracing"
+)
+
+func stubTracing(t *testing.T) {
+	t.Helper()
+	tracingInitFunc = func(ctx context.Context, opts tracing.Options) (trace.Tracer, func(context.Context) error, error) {
+		return noop.NewTracerProvider().Tracer("test"), func(context.Context) error { return nil }, nil
+	}
+	t.Cleanup(func() { tracingInitFunc = tracing.Init })
+}
+
+// recordSpans makes run use a tracer whose ended spans are kept in the
+// returned recorder.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	tracingInitFunc = func(ctx context.Context, opts tracing.Options) (trace.Tracer, func(context.Context) error, error) {
+		return tp.Tracer("test"), func(context.Context) error { return nil }, nil
+	}
+	t.Cleanup(func() { tracingInitFunc = tracing.Init })
+	return sr
+}
+
+// pipelineSpans returns the recorded sling.sync.run spans in the order they
+// ended.
+func pipelineSpans(sr *tracetest.SpanRecorder) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, s := range sr.Ended() {
+		if s.Name() == "sling.sync.run" {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// spanStatuses returns the status attribute of the recorded pipeline spans
+// keyed by pipeline file name.
+func spanStatuses(sr *tracetest.SpanRecorder) map[string]string {
+	statuses := map[string]string{}
+	for _, s := range pipelineSpans(sr) {
+		var pipeline, status string
+		for _, attr := range s.Attributes() {
+			switch attr.Key {
+			case "pipeline":
+				pipeline = filepath.Base(attr.Value.AsString())
+			case "status":
+				status = attr.Value.AsString()
+			}
+		}
+		statuses[pipeline] = status
+	}
+	return statuses
+}
+
+func writePipelines(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(""), 0644); err != nil {
+			t.Fatalf("write pipeline: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestRunConcurrencyLimit(t *testing.T) {
+	stubTracing(t)
+	dir := writePipelines(t, "a.yaml", "b.yaml", "c.yaml", "d.yaml", "e.yaml")
+
+	var inFlight, maxInFlight int32
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		mu.Lock()
+		seen[run.Pipeline] = true
+		mu.Unlock()
+		return 0, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", DataDir: t.TempDir(), SyncMode: "normal", MaxRetries: 1, BackoffBase: time.Millisecond, Concurrency: 2}
+	if err := run(testContext(), cfg); err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got != 2 {
+		t.Fatalf("max concurrent pipelines = %d, want 2", got)
+	}
+	if len(seen) != 5 {
+		t.Fatalf("expected 5 pipelines to run, got %d", len(seen))
+	}
+}
+
+func TestRunAggregatesFailures(t *testing.T) {
+	stubTracing(t)
+	dir := writePipelines(t, "a.yaml", "b.yaml", "c.yaml")
+
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		if filepath.Base(run.Pipeline) == "a.yaml" || filepath.Base(run.Pipeline) == "c.yaml" {
+			return 0, fmt.Errorf("boom")
+		}
+		return 0, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", DataDir: t.TempDir(), SyncMode: "normal", MaxRetries: 1, BackoffBase: time.Millisecond, Concurrency: 3}
+	err := run(testContext(), cfg)
+	if err == nil {
+		t.Fatalf("expected error from run")
+	}
+	for _, name := range []string{"a.yaml", "c.yaml"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not mention %s", err, name)
+		}
+	}
+	if strings.Contains(err.Error(), "b.yaml") {
+		t.Errorf("error %q mentions successful pipeline", err)
+	}
+}
+
+func TestRunSlingAttemptTimeout(t *testing.T) {
+	var deadlines []time.Duration
+	var mu sync.Mutex
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		d, ok := ctx.Deadline()
+		if !ok {
+			t.Errorf("attempt context for %s has no deadline", run.Pipeline)
+			return 0, nil
+		}
+		mu.Lock()
+		deadlines = append(deadlines, time.Until(d))
+		mu.Unlock()
+		return 0, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	span := trace.SpanFromContext(context.Background())
+	var wg sync.WaitGroup
+	for _, timeout := range []time.Duration{time.Minute, time.Hour} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg := config.Config{SlingTimeout: timeout}
+			(&runner{}).runSlingAttempt(testContext(), cfg, fmt.Sprintf("%s.yaml", timeout), "job", span)
+		}()
+	}
+	wg.Wait()
+
+	if len(deadlines) != 2 {
+		t.Fatalf("expected 2 deadlines, got %d", len(deadlines))
+	}
+	var short, long bool
+	for _, d := range deadlines {
+		short = short || d <= time.Minute
+		long = long || d > time.Minute
+	}
+	if !short || !long {
+		t.Fatalf("timeouts leaked between parallel attempts: %v", deadlines)
+	}
+}
+
+func TestRunAppliesPipelineOverrides(t *testing.T) {
+	sr := recordSpans(t)
+
+	dir := writePipelines(t, "lookups.yaml")
+	os.WriteFile(filepath.Join(dir, "telemetry.yaml"), []byte("wrapper:\n  timeout: 2h\n  sling_binary: /opt/sling\n  max_retries: 2\n"), 0644)
+
+	var mu sync.Mutex
+	binaries := map[string]string{}
+	attempts := map[string]int{}
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		d, _ := ctx.Deadline()
+		mu.Lock()
+		defer mu.Unlock()
+		name := config.PipelineName(run.Pipeline)
+		binaries[name] = run.Binary
+		attempts[name]++
+		if name == "telemetry" && time.Until(d) <= time.Hour {
+			t.Errorf("telemetry timeout not overridden, deadline in %s", time.Until(d))
+		}
+		return 0, fmt.Errorf("boom")
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	cfg := config.Config{MissionClusterID: "mc", PipelineDir: dir, StateLocation: "state", DataDir: t.TempDir(), SyncMode: "normal",
+		MaxRetries: 1, BackoffBase: time.Millisecond, SlingBinary: "sling", SlingTimeout: 2 * time.Minute, Concurrency: 2}
+	run(testContext(), cfg)
+
+	if binaries["lookups"] != "sling" || binaries["telemetry"] != "/opt/sling" {
+		t.Fatalf("unexpected binaries: %v", binaries)
+	}
+	if attempts["lookups"] != 1 || attempts["telemetry"] != 2 {
+		t.Fatalf("unexpected attempts: %v", attempts)
+	}
+
+	for _, s := range sr.Ended() {
+		attrs := map[string]string{}
+		for _, kv := range s.Attributes() {
+			attrs[string(kv.Key)] = kv.Value.Emit()
+		}
+		if config.PipelineName(attrs["pipeline"]) == "telemetry" &&
+			(attrs["sling_timeout_seconds"] != "7200" || attrs["sling_binary"] != "/opt/sling" || attrs["max_retries"] != "2") {
+			t.Fatalf("span does not report effective settings: %v", attrs)
+		}
+	}
+}
+
+func TestRunLogsCarryTraceID(t *testing.T) {
+	sr := recordSpans(t)
+	dir := writePipelines(t, "a.yaml")
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) { return 1, nil }
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	var buf bytes.Buffer
+	logger := slog.New(logging.NewHandler(&buf, lognoop.NewLoggerProvider().Logger("test")))
+	ctx := logging.NewContext(context.Background(), logger)
+	cfg := config.Config{PipelineDir: dir, StateLocation: "state", SyncMode: "normal", MaxRetries: 1, Concurrency: 1, DataDir: t.TempDir()}
+	if err := run(ctx, cfg); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	spans := sr.Ended()
+	var traceID string
+	for _, s := range spans {
+		if s.Name() == "sling.sync.run" {
+			traceID = s.SpanContext().TraceID().String()
+		}
+	}
+	var completed map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var entry map[string]any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		if entry["msg"] == "pipeline completed" {
+			completed = entry
+		}
+	}
+	if completed == nil || traceID == "" || completed["trace_id"] != traceID {
+		t.Errorf("pipeline completed log = %v, want trace_id %q", completed, traceID)
+	}
+}
+
+func TestRunContinuesInboundTrace(t *testing.T) {
+	sr := recordSpans(t)
+	dir := writePipelines(t, "a.yaml", "b.yaml")
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) { return 1, nil }
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	cfg := config.Config{PipelineDir: dir, StateLocation: "state", SyncMode: "normal", MaxRetries: 1, Concurrency: 2,
+		DataDir: t.TempDir(), ReportFile: reportFile, ReportFormat: "json",
+		TraceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}
+	if err := run(testContext(), cfg); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	var invocation sdktrace.ReadOnlySpan
+	for _, s := range sr.Ended() {
+		if s.Name() == "sling.sync.invocation" {
+			invocation = s
+		}
+	}
+	runs := pipelineSpans(sr)
+	if invocation == nil {
+		t.Fatal("no invocation span")
+	}
+	if got := invocation.Parent(); got.TraceID().String() != "0af7651916cd43dd8448eb211c80319c" ||
+		got.SpanID().String() != "b7ad6b7169203331" || !got.IsRemote() {
+		t.Errorf("invocation parent = %+v, want the inbound traceparent", got)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("got %d pipeline spans, want 2", len(runs))
+	}
+	for _, s := range runs {
+		if s.Parent().SpanID() != invocation.SpanContext().SpanID() {
+			t.Errorf("pipeline span parent = %s, want the invocation span", s.Parent().SpanID())
+		}
+	}
+
+	data, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"trace_id": "0af7651916cd43dd8448eb211c80319c"`) {
+		t.Errorf("report lacks the invocation trace ID:\n%s", data)
+	}
+}
+
+func TestRunIgnoresInvalidTraceparent(t *testing.T) {
+	sr := recordSpans(t)
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) { return 1, nil }
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	dir := writePipelines(t, "a.yaml")
+	cfg := config.Config{PipelineDir: dir, StateLocation: "state", SyncMode: "normal", MaxRetries: 1, Concurrency: 1,
+		DataDir: t.TempDir(), TraceParent: "not-a-traceparent"}
+	if err := run(testContext(), cfg); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	for _, s := range sr.Ended() {
+		if s.Name() == "sling.sync.invocation" {
+			if s.Parent().IsValid() {
+				t.Errorf("invocation parent = %+v, want a new root trace", s.Parent())
+			}
+			return
+		}
+	}
+	t.Fatal("no invocation span")
+}
+
+// failTracing makes telemetry setup fail.
+func failTracing(t *testing.T) {
+	t.Helper()
+	tracingInitFunc = func(ctx context.Context, opts tracing.Options) (trace.Tracer, func(context.Context) error, error) {
+		return nil, nil, errors.New("create OTLP trace exporter: bad endpoint")
+	}
+	t.Cleanup(func() { tracingInitFunc = tracing.Init })
+}
+
+func TestRunWithoutTelemetry(t *testing.T) {
+	failTracing(t)
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) { return 1, nil }
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	dir := writePipelines(t, "a.yaml")
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	cfg := config.Config{PipelineDir: dir, StateLocation: "state", SyncMode: "normal", MaxRetries: 1, Concurrency: 1,
+		DataDir: t.TempDir(), ReportFile: reportFile, ReportFormat: "json"}
+	if err := run(testContext(), cfg); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	data, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"telemetry_degraded": true`) {
+		t.Errorf("report does not flag degraded telemetry:\n%s", data)
+	}
+}
+
+func TestRunRequiresTelemetry(t *testing.T) {
+	failTracing(t)
+	ran := false
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		ran = true
+		return 1, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	dir := writePipelines(t, "a.yaml")
+	cfg := config.Config{PipelineDir: dir, StateLocation: "state", SyncMode: "normal", MaxRetries: 1, Concurrency: 1,
+		DataDir: t.TempDir(), RequireTelemetry: true}
+	err := run(testContext(), cfg)
+	if code := exitCode(err); code != exitFailure {
+		t.Fatalf("exit code = %d (%v), want %d", code, err, exitFailure)
+	}
+	if ran {
+		t.Errorf("pipelines ran without the required telemetry")
+	}
+}
+
+func TestRunWithInvalidTelemetrySettings(t *testing.T) {
+	stubTracing(t)
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) { return 1, nil }
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	dir := writePipelines(t, "a.yaml")
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	cfg := config.Config{PipelineDir: dir, StateLocation: "state", SyncMode: "normal", MaxRetries: 1, Concurrency: 1,
+		DataDir: t.TempDir(), ReportFile: reportFile, ReportFormat: "json", OTELProtocol: "http/json"}
+	if err := run(testContext(), cfg); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	data, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"telemetry_degraded": true`) {
+		t.Errorf("report does not flag degraded telemetry:\n%s", data)
+	}
+
+	cfg.RequireTelemetry = true
+	var cfgErr *configError
+	if err := run(testContext(), cfg); !errors.As(err, &cfgErr) {
+		t.Fatalf("run error with required telemetry = %v, want a config error", err)
+	}
+}
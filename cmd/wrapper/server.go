@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sling-sync-wrapper/internal/logging"
+)
+
+// serverShutdownTimeout bounds how long in-flight HTTP requests may delay
+// shutdown.
+const serverShutdownTimeout = 5 * time.Second
+
+var (
+	errUnknownPipeline = errors.New("unknown pipeline")
+	errAlreadyRunning  = errors.New("pipeline is already running")
+)
+
+// runRequest is the body of POST /runs.
+type runRequest struct {
+	Pipeline string `json:"pipeline"`
+	// Mode is "normal" (default) or "backfill".
+	Mode string `json:"mode"`
+}
+
+// runResponse is the reply to POST /runs.
+type runResponse struct {
+	Pipeline string `json:"pipeline"`
+	Mode     string `json:"mode"`
+	JobID    string `json:"sync_job_id"`
+}
+
+// statusResponse is the reply to GET /status.
+type statusResponse struct {
+	MissionClusterID string    `json:"mission_cluster_id"`
+	StartedAt        time.Time `json:"started_at"`
+	Ready            bool      `json:"ready"`
+	// TelemetryDegraded is set when the daemon runs without telemetry.
+	TelemetryDegraded bool             `json:"telemetry_degraded"`
+	Schedules         []scheduleStatus `json:"schedules"`
+	Pipelines         []pipelineStatus `json:"pipelines"`
+}
+
+// handler returns the HTTP API of the daemon.
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !d.ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, statusResponse{
+			MissionClusterID:  d.cfg.MissionClusterID,
+			StartedAt:         d.startedAt,
+			Ready:             d.ready.Load(),
+			TelemetryDegraded: d.degraded,
+			Schedules:         d.board.scheduleList(),
+			Pipelines:         d.board.pipelines(d.pipelineNames),
+		})
+	})
+	mux.HandleFunc("POST /runs", d.handleRun)
+	mux.Handle("GET /metrics", d.metrics.Handler())
+	return mux
+}
+
+func (d *daemon) handleRun(w http.ResponseWriter, r *http.Request) {
+	var req runRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = "normal"
+	}
+	if req.Mode != "normal" && req.Mode != "backfill" {
+		http.Error(w, fmt.Sprintf("invalid mode %q (want normal or backfill)", req.Mode), http.StatusBadRequest)
+		return
+	}
+	if !d.ready.Load() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	jobID, err := d.trigger(req.Pipeline, req.Mode)
+	switch {
+	case errors.Is(err, errUnknownPipeline):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errAlreadyRunning):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, runResponse{Pipeline: req.Pipeline, Mode: req.Mode, JobID: jobID})
+}
+
+// trigger starts a run of the named pipeline in the background and returns
+// its job ID. The run is bound to the daemon's context, not the request's.
+func (d *daemon) trigger(name, mode string) (string, error) {
+	pipeline, ok := d.pipelines[name]
+	if !ok {
+		return "", fmt.Errorf("%w %q", errUnknownPipeline, name)
+	}
+	jobID := uuid.NewString()
+	if !d.board.begin(name, activeRun{JobID: jobID, SyncMode: mode, Trigger: triggerAPI, StartedAt: time.Now()}) {
+		return "", fmt.Errorf("%w: %s", errAlreadyRunning, name)
+	}
+
+	cfg := d.cfg
+	cfg.SyncMode = mode
+	cfg = pipeline.Options.Apply(cfg)
+	r := d.newRunner()
+	ctx := d.ctx
+	logging.FromContext(ctx).Info("pipeline run requested", "pipeline", pipeline.Path, "sync_job_id", jobID, "mode", mode)
+	d.triggered.Add(1)
+	go func() {
+		defer d.triggered.Done()
+		r.runPipeline(ctx, cfg, pipeline.Path, jobID)
+	}()
+	return jobID, nil
+}
+
+// serve runs the HTTP server on ln until ctx is cancelled.
+func (d *daemon) serve(ctx context.Context, ln net.Listener) {
+	logger := logging.FromContext(ctx)
+	srv := &http.Server{Handler: d.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), serverShutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	logger.Info("HTTP server listening", "addr", ln.Addr().String())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("HTTP server failed", "err", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/metrics"
+	"sling-sync-wrapper/internal/tracing"
+)
+
+// newTestDaemon returns a ready daemon knowing the named pipelines.
+func newTestDaemon(t *testing.T, names ...string) *daemon {
+	t.Helper()
+	stubTracing(t)
+	tracer, _, _ := tracingInitFunc(context.Background(), tracing.Options{})
+	d := &daemon{
+		cfg:       config.Config{MissionClusterID: "mc", StateLocation: "state", MaxRetries: 1, Concurrency: 1},
+		tracer:    tracer,
+		ctx:       testContext(),
+		board:     newStatusBoard(),
+		metrics:   metrics.New("mc"),
+		pipelines: map[string]config.Pipeline{},
+	}
+	for _, name := range names {
+		d.pipelines[name] = config.Pipeline{Name: name, Path: name + ".yaml"}
+		d.pipelineNames = append(d.pipelineNames, name)
+	}
+	d.ready.Store(true)
+	return d
+}
+
+func doRequest(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServerHealth(t *testing.T) {
+	d := newTestDaemon(t)
+	h := d.handler()
+
+	if rec := doRequest(t, h, http.MethodGet, "/healthz", ""); rec.Code != http.StatusOK {
+		t.Fatalf("/healthz = %d, want 200", rec.Code)
+	}
+	if rec := doRequest(t, h, http.MethodGet, "/readyz", ""); rec.Code != http.StatusOK {
+		t.Fatalf("/readyz = %d, want 200", rec.Code)
+	}
+	d.ready.Store(false)
+	if rec := doRequest(t, h, http.MethodGet, "/readyz", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("/readyz while not ready = %d, want 503", rec.Code)
+	}
+	if rec := doRequest(t, h, http.MethodPost, "/runs", `{"pipeline": "a"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("POST /runs while not ready = %d, want 503", rec.Code)
+	}
+}
+
+func TestServerTriggerRun(t *testing.T) {
+	d := newTestDaemon(t, "a", "b")
+	h := d.handler()
+
+	release := make(chan struct{})
+	started := make(chan string, 1)
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+		started <- run.JobID
+		<-release
+		return 7, nil
+	}
+	defer func() { runSlingOnceFunc = runSlingOnce }()
+
+	rec := doRequest(t, h, http.MethodPost, "/runs", `{"pipeline": "a"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("POST /runs = %d %s, want 202", rec.Code, rec.Body)
+	}
+	var resp runResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if jobID := <-started; resp.JobID == "" || jobID != resp.JobID || resp.Mode != "normal" {
+		t.Fatalf("response %+v, Sling got job ID %q", resp, jobID)
+	}
+
+	if rec := doRequest(t, h, http.MethodPost, "/runs", `{"pipeline": "a"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("POST /runs while running = %d, want 409", rec.Code)
+	}
+	status := getStatus(t, h)
+	if a := status.Pipelines[0]; a.Running == nil || a.Running.JobID != resp.JobID || a.Running.Trigger != triggerAPI {
+		t.Fatalf("status of a while running = %+v", a)
+	}
+
+	close(release)
+	d.triggered.Wait()
+	status = getStatus(t, h)
+	a, b := status.Pipelines[0], status.Pipelines[1]
+	if a.Running != nil || a.Last == nil || a.Last.Status != "success" || a.Last.JobID != resp.JobID || a.Last.RowsSynced != 7 {
+		t.Fatalf("status of a after run = %+v", a)
+	}
+	if b.Name != "b" || b.Running != nil || b.Last != nil {
+		t.Fatalf("status of b = %+v, want no runs", b)
+	}
+
+	rec = doRequest(t, h, http.MethodGet, "/metrics", "")
+	if !strings.Contains(rec.Body.String(), `sling_sync_runs_total{mission_cluster_id="mc",pipeline="a",status="success"} 1`) {
+		t.Fatalf("/metrics does not count the run:\n%s", rec.Body)
+	}
+}
+
+func TestServerTriggerBackfill(t *testing.T) {
+	d := newTestDaemon(t, "a")
+	var removed []string
+	removeAllFunc = func(path string) error {
+		removed = append(removed, path)
+		return nil
+	}
+	defer func() { removeAllFunc = os.RemoveAll }()
+
+	rec := doRequest(t, d.handler(), http.MethodPost, "/runs", `{"pipeline": "a", "mode": "backfill"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("POST /runs = %d %s, want 202", rec.Code, rec.Body)
+	}
+	d.triggered.Wait()
+	if len(removed) != 1 {
+		t.Fatalf("state reset %d times, want once", len(removed))
+	}
+	if last := getStatus(t, d.handler()).Pipelines[0].Last; last == nil || last.Status != "backfill" {
+		t.Fatalf("last run = %+v, want backfill", last)
+	}
+}
+
+func TestServerRejectsBadRuns(t *testing.T) {
+	d := newTestDaemon(t, "a")
+	tests := map[string]struct {
+		body string
+		want int
+	}{
+		"unknown pipeline": {`{"pipeline": "nope"}`, http.StatusNotFound},
+		"unknown mode":     {`{"pipeline": "a", "mode": "noop"}`, http.StatusBadRequest},
+		"unknown field":    {`{"pipeline": "a", "force": true}`, http.StatusBadRequest},
+		"not JSON":         {`a`, http.StatusBadRequest},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if rec := doRequest(t, d.handler(), http.MethodPost, "/runs", tt.body); rec.Code != tt.want {
+				t.Fatalf("POST /runs = %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+	if rec := doRequest(t, d.handler(), http.MethodGet, "/runs", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET /runs = %d, want 405", rec.Code)
+	}
+}
+
+func getStatus(t *testing.T, h http.Handler) statusResponse {
+	t.Helper()
+	rec := doRequest(t, h, http.MethodGet, "/status", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/status = %d", rec.Code)
+	}
+	var status statusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	return status
+}
+
+func TestScheduledRunSkipsPipelineRunningViaAPI(t *testing.T) {
+	d := newTestDaemon(t, "a")
+	graph, err := buildGraph([]config.Pipeline{d.pipelines["a"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.board.begin("a", activeRun{JobID: "api-job", Trigger: triggerAPI})
+
+	err = d.runJob(testContext(), &scheduledJob{name: globalScheduleJob, graph: graph})
+	if got := statusFromErr(err); got != "lock_held" {
+		t.Fatalf("status = %q (%v), want lock_held", got, err)
+	}
+	if a := d.board.pipelines([]string{"a"})[0]; a.Running == nil || a.Running.JobID != "api-job" {
+		t.Fatalf("API run no longer shown as running: %+v", a)
+	}
+}
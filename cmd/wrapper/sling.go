@@ -2,17 +2,25 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/trace"
 
+	"sling-sync-wrapper/internal/config"
 	"sling-sync-wrapper/internal/logging"
+	"sling-sync-wrapper/internal/tracing"
 )
 
 var execCommandContext = exec.CommandContext
@@ -22,25 +30,139 @@ type SlingLogLine struct {
 	Level   string `json:"level"`
 	Message string `json:"message"`
 	Rows    int    `json:"rows,omitempty"`
-	Error   string `json:"error,omitempty"`
+	Bytes   int64  `json:"bytes,omitempty"`
+	// Stream names the stream (table) of a replication the line belongs to.
+	Stream string `json:"stream,omitempty"`
+	Error  string `json:"error,omitempty"`
 }
 
 const (
 	maxScanTokenSize = 1024 * 1024 // 1 MiB
 )
 
-var slingCLITimeout = 30 * time.Minute
+// slingRun describes a single invocation of the Sling CLI.
+type slingRun struct {
+	Binary        string
+	Pipeline      string
+	StateLocation string
+	JobID         string
+	// MissionClusterID is passed to Sling in the trace baggage.
+	MissionClusterID string
+	// GracePeriod is how long Sling may take to exit after receiving SIGTERM
+	// when ctx is cancelled before it is killed. Zero kills it immediately.
+	GracePeriod time.Duration
+	// Instruments count log lines that could not be parsed. May be nil.
+	Instruments *tracing.Instruments
+}
+
+// maxStderrTail is how much of Sling's stderr output is kept for error
+// classification, the attempt span and the returned error.
+const maxStderrTail = 4 * 1024
+
+// maxStderrLine bounds a stderr line that is mirrored to the log; longer
+// lines are logged in pieces.
+const maxStderrLine = 16 * 1024
+
+// slingError describes a failed Sling invocation together with the output
+// needed to classify the failure.
+type slingError struct {
+	// ExitCode is the Sling exit code, or -1 if it did not exit normally.
+	ExitCode int
+	// LogErrors holds the error fields of Sling's JSON log lines.
+	LogErrors []string
+	// Stderr holds the tail of Sling's stderr output.
+	Stderr string
+	Err    error
+}
+
+// Error includes the tail of stderr when Sling exited non-zero, since
+// panics and driver errors often only show up there.
+func (e *slingError) Error() string {
+	if e.ExitCode > 0 {
+		if tail := strings.TrimSpace(e.Stderr); tail != "" {
+			return fmt.Sprintf("%v; stderr: %s", e.Err, tail)
+		}
+	}
+	return e.Err.Error()
+}
+
+func (e *slingError) Unwrap() error { return e.Err }
+
+// tailBuffer is an io.Writer that keeps only the last max bytes written.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.max; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
+
+// stderrLogger is the stderr of a Sling process. It keeps the tail of the
+// output and mirrors each line to the wrapper log of ctx, which carries the
+// pipeline and job of the run.
+type stderrLogger struct {
+	ctx  context.Context
+	tail tailBuffer
+	line []byte
+}
+
+func newStderrLogger(ctx context.Context) *stderrLogger {
+	return &stderrLogger{ctx: ctx, tail: tailBuffer{max: maxStderrTail}}
+}
+
+// Write is called by a single goroutine of exec.Cmd, which Wait waits for.
+func (w *stderrLogger) Write(p []byte) (int, error) {
+	n := len(p)
+	w.tail.Write(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.line = append(w.line, p...)
+			if len(w.line) >= maxStderrLine {
+				w.flush()
+			}
+			break
+		}
+		w.line = append(w.line, p[:i]...)
+		w.flush()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// flush logs the pending line, if any.
+func (w *stderrLogger) flush() {
+	line := strings.TrimRight(string(w.line), "\r")
+	w.line = w.line[:0]
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	logging.FromContext(w.ctx).WarnContext(w.ctx, "sling stderr", "line", line)
+}
 
 // processLogLine parses a JSON line from the Sling CLI and updates the span.
-// It returns the number of rows contained in the log or an error if the line
-// could not be parsed.
-func processLogLine(line string, span trace.Span) (int, error) {
+// It returns the decoded entry or an error if the line could not be parsed.
+func processLogLine(line string, span trace.Span) (SlingLogLine, error) {
 	var logEntry SlingLogLine
 	if err := json.Unmarshal([]byte(line), &logEntry); err != nil {
 		span.RecordError(err)
 		span.AddEvent("invalid JSON log line",
 			trace.WithAttributes(attribute.String("line", line)))
-		return 0, fmt.Errorf("decode log line: %w", err)
+		return SlingLogLine{}, fmt.Errorf("decode log line: %w", err)
 	}
 
 	span.AddEvent(logEntry.Message,
@@ -49,7 +171,7 @@ func processLogLine(line string, span trace.Span) (int, error) {
 		span.RecordError(fmt.Errorf("%s", logEntry.Error))
 	}
 
-	return logEntry.Rows, nil
+	return logEntry, nil
 }
 
 // checkSlingErrors combines errors from the scanner, command wait, and context
@@ -71,22 +193,47 @@ func checkSlingErrors(ctx context.Context, cmd *exec.Cmd, scanErr error) error {
 	return nil
 }
 
-func runSlingOnce(ctx context.Context, slingBin, pipeline, stateLocation, jobID string, span trace.Span) (int, error) {
-	ctx, cancel := context.WithTimeout(ctx, slingCLITimeout)
-	defer cancel()
+// traceEnv returns the environment variables that let Sling and its drivers
+// continue the trace of ctx, with the mission cluster and sync job in the
+// baggage. They override any trace context the wrapper itself inherited.
+func traceEnv(ctx context.Context, run slingRun) []string {
+	bag := baggage.FromContext(ctx)
+	for _, kv := range [][2]string{
+		{"mission_cluster_id", run.MissionClusterID},
+		{"sync_job_id", run.JobID},
+	} {
+		member, err := baggage.NewMemberRaw(kv[0], kv[1])
+		if err != nil {
+			continue
+		}
+		if b, err := bag.SetMember(member); err == nil {
+			bag = b
+		}
+	}
+	return tracing.Environ(baggage.ContextWithBaggage(ctx, bag))
+}
 
-	cmd := execCommandContext(ctx, slingBin, "sync", "--config", pipeline, "--log-format", "json")
+func runSlingOnce(ctx context.Context, run slingRun, span trace.Span) (int, error) {
+	cmd := execCommandContext(ctx, run.Binary, "sync", "--config", run.Pipeline, "--log-format", "json")
 	cmd.Env = append(os.Environ(),
-		fmt.Sprintf("SLING_STATE=%s", stateLocation),
-		fmt.Sprintf("SYNC_JOB_ID=%s", jobID),
-		fmt.Sprintf("SLING_CONFIG=%s", pipeline),
+		fmt.Sprintf("SLING_STATE=%s", run.StateLocation),
+		fmt.Sprintf("SYNC_JOB_ID=%s", run.JobID),
+		fmt.Sprintf("SLING_CONFIG=%s", run.Pipeline),
 	)
+	cmd.Env = append(cmd.Env, traceEnv(ctx, run)...)
+	if run.GracePeriod > 0 {
+		// Give Sling a chance to finish its current write and release its
+		// connections; exec kills it once the grace period has elapsed.
+		cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+		cmd.WaitDelay = run.GracePeriod
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return 0, fmt.Errorf("stdout pipe: %w", err)
 	}
-	cmd.Stderr = os.Stderr
+	stderr := newStderrLogger(ctx)
+	cmd.Stderr = stderr
 
 	if err := cmd.Start(); err != nil {
 		return 0, fmt.Errorf("start sling: %w", err)
@@ -96,28 +243,70 @@ func runSlingOnce(ctx context.Context, slingBin, pipeline, stateLocation, jobID
 	buf := make([]byte, 0, maxScanTokenSize)
 	scanner.Buffer(buf, maxScanTokenSize)
 	rowsSynced := 0
+	var logErrors []string
 	logger := logging.FromContext(ctx)
+	streams := newStreamTracker(ctx, span)
 	for scanner.Scan() {
-		rows, err := processLogLine(scanner.Text(), span)
+		entry, err := processLogLine(scanner.Text(), span)
 		if err != nil {
-			logger.Error("failed to parse Sling log line", "err", err)
+			logger.ErrorContext(ctx, "failed to parse Sling log line", "err", err)
+			run.Instruments.RecordParseFailure(ctx, config.PipelineName(run.Pipeline))
 			continue
 		}
-		if rows > 0 {
-			rowsSynced += rows
+		if entry.Rows > 0 {
+			rowsSynced += entry.Rows
 		}
+		if entry.Error != "" {
+			logErrors = append(logErrors, entry.Error)
+		}
+		streams.observe(entry)
 	}
 
-	if err := checkSlingErrors(ctx, cmd, scanner.Err()); err != nil {
-		return rowsSynced, fmt.Errorf("execute sling: %w", err)
+	err = checkSlingErrors(ctx, cmd, scanner.Err())
+	stderr.flush()
+	tail := stderr.tail.String()
+	if tail != "" {
+		span.SetAttributes(attribute.String("sling.stderr", tail))
+	}
+	streams.close(err)
+	if err != nil {
+		exitCode := -1
+		if cmd.ProcessState != nil {
+			exitCode = cmd.ProcessState.ExitCode()
+		}
+		return rowsSynced, fmt.Errorf("execute sling: %w", &slingError{
+			ExitCode:  exitCode,
+			LogErrors: logErrors,
+			Stderr:    tail,
+			Err:       err,
+		})
 	}
 
 	return rowsSynced, nil
 }
 
 func statusFromErr(err error) string {
-	if err != nil {
+	var upstream *upstreamFailedError
+	var circuitOpen *circuitOpenError
+	var deferred *deferredError
+	switch {
+	case err == nil:
+		return "success"
+	case errors.As(err, &upstream):
+		return "skipped"
+	case errors.As(err, &circuitOpen):
+		return "circuit_open"
+	case errors.As(err, &deferred):
+		return "deferred"
+	case errors.Is(err, errRunDeadline):
+		return "deadline_exceeded"
+	case errors.Is(err, errLockHeld):
+		return "lock_held"
+	case errors.Is(err, errLockLost):
+		return "lock_lost"
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	default:
 		return "failed"
 	}
-	return "success"
 }
@@ -1,18 +1,25 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel/baggage"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"sling-sync-wrapper/internal/logging"
 )
 
 func fakeExecCommandContext(script string) func(context.Context, string, ...string) *exec.Cmd {
@@ -48,7 +55,7 @@ func TestRunSlingOnceEvents(t *testing.T) {
 
 	ctx := testContext()
 	ctx, span := tracer.Start(ctx, "run")
-	rows, err := runSlingOnce(ctx, script, "pipe.yaml", "state", "job", span)
+	rows, err := runSlingOnce(ctx, slingRun{Binary: script, Pipeline: "pipe.yaml", StateLocation: "state", JobID: "job"}, span)
 	span.End()
 	if err != nil {
 		t.Fatalf("runSlingOnce error: %v", err)
@@ -83,7 +90,7 @@ func TestRunSlingOnceLongLogLine(t *testing.T) {
 
 	ctx := testContext()
 	ctx, span := tracer.Start(ctx, "run")
-	if _, err := runSlingOnce(ctx, script, "pipe.yaml", "state", "job", span); err != nil {
+	if _, err := runSlingOnce(ctx, slingRun{Binary: script, Pipeline: "pipe.yaml", StateLocation: "state", JobID: "job"}, span); err != nil {
 		t.Fatalf("runSlingOnce error: %v", err)
 	}
 	span.End()
@@ -121,7 +128,7 @@ func TestRunSlingOnceEnvironmentVariables(t *testing.T) {
 
 	ctx := testContext()
 	ctx, span := tracer.Start(ctx, "run")
-	if _, err := runSlingOnce(ctx, script, "pipe.yaml", "state", "job", span); err != nil {
+	if _, err := runSlingOnce(ctx, slingRun{Binary: script, Pipeline: "pipe.yaml", StateLocation: "state", JobID: "job"}, span); err != nil {
 		t.Fatalf("runSlingOnce error: %v", err)
 	}
 	span.End()
@@ -150,6 +157,52 @@ func TestRunSlingOnceEnvironmentVariables(t *testing.T) {
 	}
 }
 
+func TestRunSlingOncePropagatesTraceContext(t *testing.T) {
+	// A trace context inherited by the wrapper must not reach Sling.
+	t.Setenv("TRACEPARENT", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	dir := t.TempDir()
+	script, err := writeScript(dir)
+	if err != nil {
+		t.Fatalf("script: %v", err)
+	}
+	var capturedCmd *exec.Cmd
+	execCommandContext = func(ctx context.Context, command string, args ...string) *exec.Cmd {
+		capturedCmd = exec.CommandContext(ctx, script)
+		return capturedCmd
+	}
+	defer func() { execCommandContext = exec.CommandContext }()
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(testContext(), "run")
+	defer span.End()
+	run := slingRun{Binary: script, Pipeline: "pipe.yaml", StateLocation: "state", JobID: "job-1", MissionClusterID: "mission 01"}
+	if _, err := runSlingOnce(ctx, run, span); err != nil {
+		t.Fatalf("runSlingOnce error: %v", err)
+	}
+
+	// exec uses the last value of duplicate keys.
+	env := map[string]string{}
+	for _, e := range capturedCmd.Env {
+		k, v, _ := strings.Cut(e, "=")
+		env[k] = v
+	}
+	sc := span.SpanContext()
+	want := fmt.Sprintf("00-%s-%s-01", sc.TraceID(), sc.SpanID())
+	if env["TRACEPARENT"] != want {
+		t.Errorf("TRACEPARENT = %q, want %q", env["TRACEPARENT"], want)
+	}
+	bag, err := baggage.Parse(env["BAGGAGE"])
+	if err != nil {
+		t.Fatalf("BAGGAGE %q: %v", env["BAGGAGE"], err)
+	}
+	if got := bag.Member("mission_cluster_id").Value(); got != "mission 01" {
+		t.Errorf("baggage mission_cluster_id = %q", got)
+	}
+	if got := bag.Member("sync_job_id").Value(); got != "job-1" {
+		t.Errorf("baggage sync_job_id = %q", got)
+	}
+}
+
 func TestRunSlingOnceInvalidJSON(t *testing.T) {
 	dir := t.TempDir()
 	script := filepath.Join(dir, "sling")
@@ -166,7 +219,7 @@ func TestRunSlingOnceInvalidJSON(t *testing.T) {
 
 	ctx := testContext()
 	ctx, span := tracer.Start(ctx, "run")
-	if _, err := runSlingOnce(ctx, script, "pipe.yaml", "state", "job", span); err != nil {
+	if _, err := runSlingOnce(ctx, slingRun{Binary: script, Pipeline: "pipe.yaml", StateLocation: "state", JobID: "job"}, span); err != nil {
 		t.Fatalf("runSlingOnce error: %v", err)
 	}
 	span.End()
@@ -205,7 +258,7 @@ func TestRunSlingOnceTimeout(t *testing.T) {
 	ctx, cancel := context.WithTimeout(testContext(), 10*time.Millisecond)
 	defer cancel()
 	ctx, span := tracer.Start(ctx, "run")
-	_, err := runSlingOnce(ctx, script, "pipe.yaml", "state", "job", span)
+	_, err := runSlingOnce(ctx, slingRun{Binary: script, Pipeline: "pipe.yaml", StateLocation: "state", JobID: "job"}, span)
 	span.End()
 	if err == nil {
 		t.Fatalf("expected timeout error")
@@ -214,3 +267,148 @@ func TestRunSlingOnceTimeout(t *testing.T) {
 		t.Fatalf("expected deadline exceeded error, got %v", err)
 	}
 }
+
+func TestRunSlingOnceGracefulCancel(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "terminated")
+	script := filepath.Join(dir, "sling")
+	content := fmt.Sprintf("#!/bin/sh\ntrap 'echo done > %s; exit 0' TERM\nsleep 5 >/dev/null &\nwait\n", marker)
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("script: %v", err)
+	}
+	execCommandContext = fakeExecCommandContext(script)
+	defer func() { execCommandContext = exec.CommandContext }()
+
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	ctx, cancel := context.WithCancel(testContext())
+	ctx, span := tracer.Start(ctx, "run")
+	time.AfterFunc(200*time.Millisecond, cancel)
+	_, err := runSlingOnce(ctx, slingRun{Binary: script, Pipeline: "pipe.yaml", GracePeriod: 2 * time.Second}, span)
+	span.End()
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancelled error, got %v", err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("sling did not receive SIGTERM: %v", err)
+	}
+}
+
+func TestRunSlingOnceKillsAfterGracePeriod(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "sling")
+	content := "#!/bin/sh\ntrap '' TERM\nsleep 5 >/dev/null &\nwait\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("script: %v", err)
+	}
+	execCommandContext = fakeExecCommandContext(script)
+	defer func() { execCommandContext = exec.CommandContext }()
+
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	ctx, cancel := context.WithCancel(testContext())
+	ctx, span := tracer.Start(ctx, "run")
+	time.AfterFunc(200*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := runSlingOnce(ctx, slingRun{Binary: script, Pipeline: "pipe.yaml", GracePeriod: 100 * time.Millisecond}, span)
+	span.End()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("sling was not killed after the grace period (took %s)", elapsed)
+	}
+}
+
+func TestRunSlingOnceErrorDetails(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "sling")
+	content := "#!/bin/sh\n" +
+		"echo '{\"level\":\"error\",\"message\":\"fail\",\"error\":\"no such table: telemetry\"}'\n" +
+		"echo 'driver: connection closed' >&2\n" +
+		"exit 3\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("script: %v", err)
+	}
+	execCommandContext = fakeExecCommandContext(script)
+	defer func() { execCommandContext = exec.CommandContext }()
+
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	ctx, span := tracer.Start(testContext(), "run")
+	_, err := runSlingOnce(ctx, slingRun{Binary: script, Pipeline: "pipe.yaml"}, span)
+	span.End()
+
+	var slingErr *slingError
+	if !errors.As(err, &slingErr) {
+		t.Fatalf("expected slingError, got %v", err)
+	}
+	if slingErr.ExitCode != 3 {
+		t.Errorf("exit code = %d, want 3", slingErr.ExitCode)
+	}
+	if len(slingErr.LogErrors) != 1 || slingErr.LogErrors[0] != "no such table: telemetry" {
+		t.Errorf("unexpected log errors: %v", slingErr.LogErrors)
+	}
+	if !strings.Contains(slingErr.Stderr, "connection closed") {
+		t.Errorf("stderr not captured: %q", slingErr.Stderr)
+	}
+}
+
+func TestRunSlingOnceCapturesStderr(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "sling")
+	content := "#!/bin/sh\n" +
+		"echo 'panic: runtime error: invalid memory address' >&2\n" +
+		"printf 'goroutine 1 [running]:' >&2\n" +
+		"exit 2\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("script: %v", err)
+	}
+	execCommandContext = fakeExecCommandContext(script)
+	defer func() { execCommandContext = exec.CommandContext }()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil)).With("pipeline", "pipe.yaml", "sync_job_id", "job")
+	ctx := logging.NewContext(context.Background(), logger)
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+	ctx, span := tracer.Start(ctx, "attempt")
+	_, err := runSlingOnce(ctx, slingRun{Binary: script, Pipeline: "pipe.yaml", JobID: "job"}, span)
+	span.End()
+
+	if err == nil || !strings.Contains(err.Error(), "stderr: panic: runtime error") {
+		t.Errorf("error lacks the stderr tail: %v", err)
+	}
+	var lines []string
+	for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(l), &entry); err != nil {
+			t.Fatalf("log line %q: %v", l, err)
+		}
+		if entry["msg"] != "sling stderr" {
+			continue
+		}
+		if entry["pipeline"] != "pipe.yaml" || entry["sync_job_id"] != "job" {
+			t.Errorf("stderr log lacks the run fields: %v", entry)
+		}
+		lines = append(lines, entry["line"].(string))
+	}
+	want := []string{"panic: runtime error: invalid memory address", "goroutine 1 [running]:"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("logged stderr lines = %q, want %q", lines, want)
+	}
+	var attr string
+	for _, kv := range sr.Ended()[0].Attributes() {
+		if kv.Key == "sling.stderr" {
+			attr = kv.Value.AsString()
+		}
+	}
+	if !strings.Contains(attr, "goroutine 1 [running]:") {
+		t.Errorf("span stderr = %q", attr)
+	}
+}
+
+func TestTailBufferKeepsTail(t *testing.T) {
+	b := &tailBuffer{max: 8}
+	b.Write([]byte("0123456789"))
+	b.Write([]byte("ab"))
+	if got := b.String(); got != "456789ab" {
+		t.Errorf("tail = %q, want 456789ab", got)
+	}
+}
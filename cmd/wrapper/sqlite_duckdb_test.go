@@ -37,7 +37,7 @@ func TestSQLiteToDuckDBSync(t *testing.T) {
 
 	cfg := config.Config{MissionClusterID: "mc", StateLocation: filepath.Join(tmp, "state"), SyncMode: "normal", MaxRetries: 1, BackoffBase: time.Millisecond}
 
-	runSlingOnceFunc = func(ctx context.Context, bin, pipeline, state, jobID string, span trace.Span) (int, error) {
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
 		if err := sampledb.EnsureCommandTable(commandPath, false); err != nil {
 			return 0, err
 		}
@@ -45,7 +45,7 @@ func TestSQLiteToDuckDBSync(t *testing.T) {
 	}
 	defer func() { runSlingOnceFunc = runSlingOnce }()
 
-	if err := runPipeline(testContext(), tracer, cfg, pipelinePath, "job1"); err != nil {
+	if err := (&runner{tracer: tracer}).runPipeline(testContext(), cfg, pipelinePath, "job1"); err != nil {
 		t.Fatalf("runPipeline returned error: %v", err)
 	}
 
@@ -95,7 +95,7 @@ func TestSQLiteTwoMissionDBsToDuckDB(t *testing.T) {
 
 	var srcPath string
 	var currentMission string
-	runSlingOnceFunc = func(ctx context.Context, bin, pipeline, state, jobID string, span trace.Span) (int, error) {
+	runSlingOnceFunc = func(ctx context.Context, run slingRun, span trace.Span) (int, error) {
 		if err := sampledb.EnsureCommandTable(commandPath, true); err != nil {
 			return 0, err
 		}
@@ -105,12 +105,12 @@ func TestSQLiteTwoMissionDBsToDuckDB(t *testing.T) {
 
 	srcPath = mission1Path
 	currentMission = "mission1"
-	if err := runPipeline(testContext(), tracer, cfg, pipeline1, "job1"); err != nil {
+	if err := (&runner{tracer: tracer}).runPipeline(testContext(), cfg, pipeline1, "job1"); err != nil {
 		t.Fatalf("runPipeline returned error: %v", err)
 	}
 	srcPath = mission2Path
 	currentMission = "mission2"
-	if err := runPipeline(testContext(), tracer, cfg, pipeline2, "job2"); err != nil {
+	if err := (&runner{tracer: tracer}).runPipeline(testContext(), cfg, pipeline2, "job2"); err != nil {
 		t.Fatalf("runPipeline returned error: %v", err)
 	}
 
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"sling-sync-wrapper/internal/report"
+)
+
+// Run triggers reported on the status board.
+const (
+	triggerSchedule = "schedule"
+	triggerAPI      = "api"
+)
+
+// activeRun describes a pipeline run in progress.
+type activeRun struct {
+	JobID     string    `json:"sync_job_id"`
+	SyncMode  string    `json:"sync_mode"`
+	Trigger   string    `json:"trigger"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// pipelineStatus is the status of one pipeline.
+type pipelineStatus struct {
+	Name    string           `json:"name"`
+	Running *activeRun       `json:"running,omitempty"`
+	Last    *report.Pipeline `json:"last,omitempty"`
+}
+
+// scheduleStatus is the status of one scheduled job.
+type scheduleStatus struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	NextRun  time.Time `json:"next_run"`
+	LastRun  time.Time `json:"last_run,omitzero"`
+}
+
+// statusBoard tracks the current and last run of each pipeline of a
+// long-running process. A nil board ignores all updates so that one-shot runs
+// need not keep one.
+type statusBoard struct {
+	mu        sync.Mutex
+	running   map[string]activeRun
+	last      map[string]report.Pipeline
+	schedules map[string]scheduleStatus
+}
+
+func newStatusBoard() *statusBoard {
+	return &statusBoard{
+		running:   map[string]activeRun{},
+		last:      map[string]report.Pipeline{},
+		schedules: map[string]scheduleStatus{},
+	}
+}
+
+// begin marks pipeline as running. It reports false, without changing the
+// board, if the pipeline is already running.
+func (b *statusBoard) begin(pipeline string, run activeRun) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.running[pipeline]; ok {
+		return false
+	}
+	b.running[pipeline] = run
+	return true
+}
+
+// finish records the outcome of a pipeline run and ends it if it was begun
+// with the same job ID.
+func (b *statusBoard) finish(res *report.Pipeline) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.last[res.Name] = *res
+	if run, ok := b.running[res.Name]; ok && run.JobID == res.JobID {
+		delete(b.running, res.Name)
+	}
+}
+
+// scheduled records the next and last run of a scheduled job.
+func (b *statusBoard) scheduled(s scheduleStatus) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.schedules[s.Name] = s
+}
+
+// pipelines returns the status of the named pipelines in the given order.
+func (b *statusBoard) pipelines(names []string) []pipelineStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]pipelineStatus, 0, len(names))
+	for _, name := range names {
+		s := pipelineStatus{Name: name}
+		if run, ok := b.running[name]; ok {
+			s.Running = &run
+		}
+		if last, ok := b.last[name]; ok {
+			s.Last = &last
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// scheduleList returns the status of all scheduled jobs sorted by name.
+func (b *statusBoard) scheduleList() []scheduleStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]scheduleStatus, 0, len(b.schedules))
+	for _, s := range b.schedules {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
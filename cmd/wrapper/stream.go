@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// streamStartRe matches the message Sling logs when it starts a stream
+	// of a replication, e.g. `[1 / 3] running stream "public.users"`.
+	streamStartRe = regexp.MustCompile(`(?i)\brunning stream\s+"?([^"\s]+)"?`)
+	// streamEndRe matches the messages Sling logs when a stream is done.
+	streamEndRe = regexp.MustCompile(`(?i)\b(?:stream|execution)\s+(succeeded|finished|completed|failed)\b`)
+)
+
+// streamTracker opens a sling.sync.stream child span for each stream (table)
+// that a Sling replication reports in its log, so that slow or failing tables
+// show up in the trace. Streams are identified by the stream field of a log
+// line or by Sling's start message; lines without a stream belong to the
+// stream started last.
+type streamTracker struct {
+	ctx     context.Context
+	tracer  trace.Tracer
+	streams map[string]*streamSpan
+	current string
+}
+
+type streamSpan struct {
+	span  trace.Span
+	start time.Time
+	rows  int
+	bytes int64
+	err   error
+}
+
+// newStreamTracker returns a tracker whose spans are children of parent.
+func newStreamTracker(ctx context.Context, parent trace.Span) *streamTracker {
+	return &streamTracker{
+		ctx:     trace.ContextWithSpan(ctx, parent),
+		tracer:  parent.TracerProvider().Tracer("sling-sync-wrapper"),
+		streams: map[string]*streamSpan{},
+	}
+}
+
+// observe attributes a parsed log line to its stream, starting and finishing
+// stream spans as Sling reports them.
+func (t *streamTracker) observe(entry SlingLogLine) {
+	name := entry.Stream
+	if name == "" {
+		if m := streamStartRe.FindStringSubmatch(entry.Message); m != nil {
+			name = m[1]
+		}
+	}
+	if name == "" {
+		name = t.current
+	}
+	if name == "" {
+		return
+	}
+
+	s := t.streams[name]
+	if s == nil {
+		_, span := t.tracer.Start(t.ctx, "sling.sync.stream", trace.WithAttributes(attribute.String("stream", name)))
+		s = &streamSpan{span: span, start: time.Now()}
+		t.streams[name] = s
+	}
+	t.current = name
+	if entry.Rows > 0 {
+		s.rows += entry.Rows
+	}
+	if entry.Bytes > 0 {
+		s.bytes += entry.Bytes
+	}
+	if entry.Error != "" {
+		s.err = errors.New(entry.Error)
+	}
+	if m := streamEndRe.FindStringSubmatch(entry.Message); m != nil {
+		if s.err == nil && m[1] == "failed" {
+			s.err = fmt.Errorf("%s", entry.Message)
+		}
+		t.finish(name, nil)
+	}
+}
+
+// close finishes the streams that are still open when Sling exits. runErr is
+// the error Sling exited with, which also fails the unfinished streams.
+func (t *streamTracker) close(runErr error) {
+	for name := range t.streams {
+		t.finish(name, runErr)
+	}
+}
+
+func (t *streamTracker) finish(name string, runErr error) {
+	s := t.streams[name]
+	delete(t.streams, name)
+	if t.current == name {
+		t.current = ""
+	}
+	err := s.err
+	if err == nil {
+		err = runErr
+	}
+	status := "success"
+	if err != nil {
+		status = "failed"
+		s.span.RecordError(err)
+	}
+	s.span.SetAttributes(
+		attribute.Int("rows_synced", s.rows),
+		attribute.Int64("bytes", s.bytes),
+		attribute.Float64("duration_seconds", time.Since(s.start).Seconds()),
+		attribute.String("status", status),
+	)
+	s.span.End()
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// streamSpans returns the attributes of the recorded stream spans keyed by
+// stream name, and checks that they are children of parent.
+func streamSpans(t *testing.T, sr *tracetest.SpanRecorder, parent sdktrace.ReadOnlySpan) map[string]map[string]string {
+	t.Helper()
+	out := map[string]map[string]string{}
+	for _, s := range sr.Ended() {
+		if s.Name() != "sling.sync.stream" {
+			continue
+		}
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("stream span is not a child of %s", parent.Name())
+		}
+		attrs := map[string]string{}
+		for _, kv := range s.Attributes() {
+			attrs[string(kv.Key)] = kv.Value.Emit()
+		}
+		out[attrs["stream"]] = attrs
+	}
+	return out
+}
+
+func TestStreamTracker(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+	ctx, span := tracer.Start(testContext(), "attempt")
+
+	streams := newStreamTracker(ctx, span)
+	for _, entry := range []SlingLogLine{
+		{Message: "connecting to source"},
+		{Message: `[1 / 3] running stream "public.users"`},
+		{Message: "wrote rows", Rows: 10, Bytes: 2048},
+		{Message: "wrote rows", Rows: 5, Bytes: 1024},
+		{Message: "execution succeeded"},
+		{Message: "writing", Stream: "public.orders", Rows: 7},
+		{Message: "execution failed", Stream: "public.orders", Error: "duplicate key"},
+		{Message: `[3 / 3] running stream "public.items"`},
+		{Message: "wrote rows", Rows: 1},
+	} {
+		streams.observe(entry)
+	}
+	streams.close(errors.New("signal: killed"))
+	span.End()
+
+	got := streamSpans(t, sr, sr.Ended()[len(sr.Ended())-1])
+	want := map[string]map[string]string{
+		"public.users":  {"rows_synced": "15", "bytes": "3072", "status": "success"},
+		"public.orders": {"rows_synced": "7", "bytes": "0", "status": "failed"},
+		"public.items":  {"rows_synced": "1", "status": "failed"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("stream spans = %v, want %d", got, len(want))
+	}
+	for stream, attrs := range want {
+		for k, v := range attrs {
+			if got[stream][k] != v {
+				t.Errorf("%s %s = %q, want %q", stream, k, got[stream][k], v)
+			}
+		}
+		if got[stream]["duration_seconds"] == "" {
+			t.Errorf("%s lacks a duration", stream)
+		}
+	}
+}
+
+func TestRunSlingOnceStreamSpans(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "sling")
+	content := "#!/bin/sh\n" +
+		"echo '{\"level\":\"info\",\"message\":\"[1 / 2] running stream \\\"public.users\\\"\"}'\n" +
+		"echo '{\"level\":\"info\",\"message\":\"wrote rows\",\"rows\":3,\"bytes\":300}'\n" +
+		"echo '{\"level\":\"info\",\"message\":\"execution succeeded\"}'\n" +
+		"echo '{\"level\":\"info\",\"message\":\"[2 / 2] running stream \\\"public.orders\\\"\"}'\n" +
+		"echo '{\"level\":\"info\",\"message\":\"wrote rows\",\"rows\":4}'\n" +
+		"echo '{\"level\":\"info\",\"message\":\"execution succeeded\"}'\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("script: %v", err)
+	}
+	execCommandContext = fakeExecCommandContext(script)
+	defer func() { execCommandContext = exec.CommandContext }()
+
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+	ctx, span := tracer.Start(testContext(), "attempt")
+	rows, err := runSlingOnce(ctx, slingRun{Binary: script, Pipeline: "pipe.yaml", StateLocation: "state", JobID: "job"}, span)
+	span.End()
+	if err != nil {
+		t.Fatalf("runSlingOnce error: %v", err)
+	}
+	if rows != 7 {
+		t.Errorf("rows = %d, want 7", rows)
+	}
+
+	got := streamSpans(t, sr, sr.Ended()[len(sr.Ended())-1])
+	if got["public.users"]["rows_synced"] != "3" || got["public.users"]["bytes"] != "300" ||
+		got["public.orders"]["rows_synced"] != "4" || got["public.orders"]["status"] != "success" {
+		t.Errorf("stream spans = %v", got)
+	}
+}
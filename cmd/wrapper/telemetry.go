@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"sling-sync-wrapper/internal/config"
+	"sling-sync-wrapper/internal/logging"
+	"sling-sync-wrapper/internal/tracing"
+)
+
+// spanSpoolDir is the directory of undelivered spans in the data dir.
+const spanSpoolDir = "span_spool"
+
+// openSpanSpool returns the spool of undelivered spans, or nil if spooling is
+// disabled.
+func openSpanSpool(cfg config.Config) *tracing.Spool {
+	if cfg.SpanSpoolMaxBytes <= 0 {
+		return nil
+	}
+	return tracing.NewSpool(filepath.Join(config.DataDir(cfg), spanSpoolDir), int64(cfg.SpanSpoolMaxBytes))
+}
+
+// telemetryOptions returns the telemetry settings of cfg.
+func telemetryOptions(cfg config.Config) tracing.Options {
+	opts := tracing.Options{
+		ServiceName:       "sling-sync-wrapper",
+		MissionClusterID:  cfg.MissionClusterID,
+		Exporter:          cfg.TelemetryExporter,
+		File:              cfg.TelemetryFile,
+		Protocol:          cfg.OTELProtocol,
+		Endpoint:          cfg.OTELEndpoint,
+		Headers:           cfg.OTELHeaders,
+		Certificate:       cfg.OTELCertificate,
+		ClientCertificate: cfg.OTELClientCertificate,
+		ClientKey:         cfg.OTELClientKey,
+		Compression:       cfg.OTELCompression,
+		Timeout:           cfg.OTELTimeout,
+		Sampler:           cfg.TracesSampler,
+		SamplerArg:        cfg.TracesSamplerArg,
+	}
+	if opts.Exporter == "" || opts.Exporter == "otlp" {
+		opts.Spool = openSpanSpool(cfg)
+	}
+	return opts
+}
+
+// initTracing sets up telemetry for a run. If the settings are invalid or
+// telemetry cannot be set up otherwise, the run goes ahead with a no-op tracer
+// and degraded reports true, unless cfg.RequireTelemetry asks for the failure
+// to be returned instead.
+func initTracing(ctx context.Context, cfg config.Config) (tracer trace.Tracer, shutdown func(context.Context) error, degraded bool, err error) {
+	opts := telemetryOptions(cfg)
+	if err := opts.Validate(); err != nil {
+		err = fmt.Errorf("invalid telemetry settings: %w", err)
+		if cfg.RequireTelemetry {
+			return nil, nil, false, &configError{err}
+		}
+		return noopTracing(ctx, err)
+	}
+	tracer, shutdown, err = tracingInitFunc(ctx, opts)
+	if err == nil {
+		return tracer, shutdown, false, nil
+	}
+	if cfg.RequireTelemetry {
+		return nil, nil, false, fmt.Errorf("init telemetry: %w", err)
+	}
+	return noopTracing(ctx, err)
+}
+
+// noopTracing logs why telemetry is unavailable and returns the no-op tracer
+// a degraded run uses.
+func noopTracing(ctx context.Context, cause error) (trace.Tracer, func(context.Context) error, bool, error) {
+	logging.FromContext(ctx).Warn("telemetry unavailable, continuing without it", "err", cause)
+	tracer := noop.NewTracerProvider().Tracer("sling-sync-wrapper")
+	return tracer, func(context.Context) error { return nil }, true, nil
+}
+
+func newTelemetryCmd(cfg *config.Config, logger *slog.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "telemetry",
+		Short: "Manage telemetry kept on this cluster",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "flush",
+		Short: "Deliver the spooled spans to the collector",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := telemetryOptions(*cfg)
+			if err := opts.Validate(); err != nil {
+				return &configError{fmt.Errorf("invalid telemetry settings: %w", err)}
+			}
+			// Flush the spool even when spooling is off now.
+			opts.Spool = tracing.NewSpool(filepath.Join(config.DataDir(*cfg), spanSpoolDir), int64(cfg.SpanSpoolMaxBytes))
+			sent, err := tracing.FlushSpool(cmd.Context(), opts)
+			left, size, pendingErr := opts.Spool.Pending()
+			if pendingErr != nil && err == nil {
+				err = pendingErr
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "flushed %d span batches, %d left (%d bytes)\n", sent, left, size)
+			if err != nil {
+				return fmt.Errorf("flush span spool: %w", err)
+			}
+			logger.Info("span spool flushed", "batches", sent)
+			return nil
+		},
+	})
+
+	return cmd
+}
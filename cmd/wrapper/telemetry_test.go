@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+
+	"sling-sync-wrapper/internal/tracing"
+)
+
+func TestTelemetryFlushCmd(t *testing.T) {
+	dataDir := t.TempDir()
+	spool := tracing.NewSpool(filepath.Join(dataDir, spanSpoolDir), 1<<20)
+	if err := spool.Add([]*tracepb.ResourceSpans{{}}); err != nil {
+		t.Fatalf("spool: %v", err)
+	}
+
+	// Nothing listens on the endpoint, so the batch stays in the spool.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	endpoint := lis.Addr().String()
+	lis.Close()
+	t.Setenv("SYNC_DATA_DIR", dataDir)
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", endpoint)
+
+	var out bytes.Buffer
+	cmd := newRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"telemetry", "flush"})
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("flush to an unreachable collector succeeded")
+	}
+	if !strings.Contains(out.String(), "flushed 0 span batches, 1 left") {
+		t.Errorf("unexpected flush output: %q", out.String())
+	}
+}
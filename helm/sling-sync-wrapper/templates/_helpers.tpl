@@ -9,3 +9,76 @@
 {{- define "sling-sync-wrapper.name" -}}
 {{ .Chart.Name }}
 {{- end -}}
+
+{{/*
+  Environment shared by the CronJob and the daemon Deployment.
+*/}}
+{{- define "sling-sync-wrapper.env" -}}
+- name: MISSION_CLUSTER_ID
+  value: {{ .Values.missionClusterId | quote }}
+- name: PIPELINE_DIR
+  value: {{ .Values.pipelineDir | quote }}
+- name: SLING_STATE
+  value: {{ .Values.slingState | quote }}
+- name: SYNC_DATA_DIR
+  value: {{ .Values.dataDir.path | quote }}
+- name: OTEL_EXPORTER_OTLP_ENDPOINT
+  value: {{ .Values.otelExporterEndpoint | quote }}
+- name: OTEL_EXPORTER_OTLP_PROTOCOL
+  value: {{ .Values.otelExporterProtocol | quote }}
+- name: OTEL_TRACES_SAMPLER
+  value: {{ .Values.tracesSampler | quote }}
+- name: SYNC_REQUIRE_TELEMETRY
+  value: {{ .Values.requireTelemetry | quote }}
+- name: SYNC_SPAN_SPOOL_MAX_BYTES
+  value: {{ .Values.spanSpoolMaxBytes | int | quote }}
+- name: SYNC_MODE
+  value: {{ .Values.syncMode | quote }}
+- name: SYNC_MAX_RETRIES
+  value: {{ .Values.syncMaxRetries | quote }}
+- name: SYNC_BACKOFF_BASE
+  value: {{ .Values.syncBackoffBase | quote }}
+- name: SYNC_BACKOFF_MAX
+  value: {{ .Values.syncBackoffMax | quote }}
+- name: SYNC_BACKOFF_JITTER
+  value: {{ .Values.syncBackoffJitter | quote }}
+- name: SLING_TIMEOUT
+  value: {{ .Values.slingTimeout | quote }}
+- name: SYNC_CONCURRENCY
+  value: {{ .Values.syncConcurrency | quote }}
+- name: SYNC_SHUTDOWN_GRACE
+  value: {{ .Values.shutdownGrace | quote }}
+- name: SYNC_BREAKER_THRESHOLD
+  value: {{ .Values.syncBreakerThreshold | quote }}
+- name: SYNC_BREAKER_COOLDOWN
+  value: {{ .Values.syncBreakerCooldown | quote }}
+- name: SYNC_RUN_DEADLINE
+  value: {{ .Values.syncRunDeadline | quote }}
+- name: SYNC_TERMINATION_LOG
+  value: {{ .Values.terminationLog | quote }}
+- name: SYNC_LOCK_BACKEND
+  value: {{ .Values.syncLockBackend | quote }}
+{{- with .Values.syncLockDsn }}
+- name: SYNC_LOCK_DSN
+  value: {{ . | quote }}
+{{- end }}
+- name: SYNC_WAIT_FOR_LOCK
+  value: {{ .Values.syncWaitForLock | quote }}
+{{- with .Values.pushgatewayUrl }}
+- name: SYNC_PUSHGATEWAY_URL
+  value: {{ . | quote }}
+{{- end }}
+{{- end -}}
+
+{{/*
+  Volume of the data dir shared by the CronJob and the daemon Deployment.
+*/}}
+{{- define "sling-sync-wrapper.dataVolume" -}}
+- name: sling-data
+{{- if .Values.dataDir.existingClaim }}
+  persistentVolumeClaim:
+    claimName: {{ .Values.dataDir.existingClaim }}
+{{- else }}
+  emptyDir: {}
+{{- end }}
+{{- end -}}
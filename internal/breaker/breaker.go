@@ -0,0 +1,224 @@
+package breaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"sling-sync-wrapper/internal/fsutil"
+)
+
+// State is the state of a pipeline's circuit breaker.
+type State string
+
+const (
+	// StateClosed lets the pipeline run normally.
+	StateClosed State = "closed"
+	// StateOpen skips the pipeline until the cool-down has elapsed.
+	StateOpen State = "open"
+	// StateHalfOpen lets a single trial run decide whether to close the
+	// circuit again. Other runs are skipped while the trial is in flight.
+	StateHalfOpen State = "half_open"
+)
+
+// Record is the persisted health of one pipeline.
+type Record struct {
+	State               State     `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	OpenedAt            time.Time `json:"opened_at,omitempty"`
+	LastFailure         time.Time `json:"last_failure,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+	// LastDurationSeconds is how long the last successful run took.
+	LastDurationSeconds float64 `json:"last_duration_seconds,omitempty"`
+}
+
+// Store tracks pipeline health across runs in a JSON file. It is safe for
+// concurrent use.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	threshold int
+	cooldown  time.Duration
+	records   map[string]*Record
+	// trials holds the pipelines whose half-open trial is in flight in this
+	// process.
+	trials map[string]bool
+}
+
+// Open loads the store at path, starting empty if the file does not exist. A
+// circuit opens after threshold consecutive failures and stays open for
+// cooldown. A threshold of zero or less disables the breaker.
+func Open(path string, threshold int, cooldown time.Duration) (*Store, error) {
+	s := &Store{path: path, threshold: threshold, cooldown: cooldown, records: map[string]*Record{}, trials: map[string]bool{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read health store: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return nil, fmt.Errorf("parse health store %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Allow reports whether pipeline may run at now and the breaker state it runs
+// under. An open circuit whose cool-down has elapsed moves to half-open. A
+// half-open circuit allows one trial until its result is recorded or the trial
+// is released.
+func (s *Store) Allow(pipeline string, now time.Time) (State, bool, error) {
+	if s.threshold <= 0 {
+		return StateClosed, true, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.records[pipeline]
+	if r == nil {
+		return StateClosed, true, nil
+	}
+	switch r.State {
+	case StateOpen:
+		if now.Sub(r.OpenedAt) < s.cooldown {
+			return StateOpen, false, nil
+		}
+		r.State = StateHalfOpen
+		s.trials[pipeline] = true
+		return StateHalfOpen, true, s.saveLocked()
+	case StateHalfOpen:
+		if s.trials[pipeline] {
+			return StateHalfOpen, false, nil
+		}
+		s.trials[pipeline] = true
+		return StateHalfOpen, true, nil
+	}
+	return StateClosed, true, nil
+}
+
+// RecordSuccess closes the circuit of pipeline and remembers how long the
+// run took. Durations are kept even when the breaker is disabled.
+func (s *Store) RecordSuccess(pipeline string, now time.Time, took time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.trials, pipeline)
+	r := s.record(pipeline)
+	r.State = StateClosed
+	r.ConsecutiveFailures = 0
+	r.OpenedAt = time.Time{}
+	r.LastSuccess = now
+	r.LastDurationSeconds = took.Seconds()
+	return s.saveLocked()
+}
+
+// LastDuration returns the duration of the last successful run of pipeline,
+// or zero if none is known.
+func (s *Store) LastDuration(pipeline string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.records[pipeline]
+	if r == nil {
+		return 0
+	}
+	return time.Duration(r.LastDurationSeconds * float64(time.Second))
+}
+
+// RecordFailure counts a failed run of pipeline and opens its circuit once
+// the threshold is reached or a half-open trial failed. It returns the
+// resulting state.
+func (s *Store) RecordFailure(pipeline string, now time.Time) (State, error) {
+	if s.threshold <= 0 {
+		return StateClosed, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.trials, pipeline)
+	r := s.record(pipeline)
+	r.ConsecutiveFailures++
+	r.LastFailure = now
+	if r.State == StateHalfOpen || r.ConsecutiveFailures >= s.threshold {
+		r.State = StateOpen
+		r.OpenedAt = now
+	}
+	return r.State, s.saveLocked()
+}
+
+// Release ends the half-open trial of pipeline without a result, so that the
+// next run may try again. It does nothing if no trial is in flight.
+func (s *Store) Release(pipeline string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.trials, pipeline)
+}
+
+// Reset closes the circuits of the given pipelines, or of all pipelines if
+// none are given.
+func (s *Store) Reset(pipelines ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(pipelines) == 0 {
+		s.records = map[string]*Record{}
+		s.trials = map[string]bool{}
+	}
+	for _, p := range pipelines {
+		delete(s.records, p)
+		delete(s.trials, p)
+	}
+	return s.saveLocked()
+}
+
+// Records returns a copy of all records keyed by pipeline name.
+func (s *Store) Records() map[string]Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]Record, len(s.records))
+	for name, r := range s.records {
+		out[name] = *r
+	}
+	return out
+}
+
+// Names returns the pipeline names in the store in sorted order.
+func (s *Store) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.records))
+	for name := range s.records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Store) record(pipeline string) *Record {
+	r := s.records[pipeline]
+	if r == nil {
+		r = &Record{State: StateClosed}
+		s.records[pipeline] = r
+	}
+	return r
+}
+
+// saveLocked writes the store atomically. s.mu must be held.
+func (s *Store) saveLocked() error {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode health store: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("write health store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package breaker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	s, err := Open(path, 2, time.Hour)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	now := time.Now()
+
+	if state, _ := s.RecordFailure("p", now); state != StateClosed {
+		t.Fatalf("state after one failure = %s, want closed", state)
+	}
+	if state, _ := s.RecordFailure("p", now); state != StateOpen {
+		t.Fatalf("state after two failures = %s, want open", state)
+	}
+	if state, ok, _ := s.Allow("p", now.Add(time.Minute)); ok || state != StateOpen {
+		t.Fatalf("open circuit allowed run (state %s)", state)
+	}
+	if _, ok, _ := s.Allow("other", now); !ok {
+		t.Fatalf("unrelated pipeline was blocked")
+	}
+}
+
+func TestBreakerPersistsAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	s, _ := Open(path, 1, time.Hour)
+	now := time.Now()
+	s.RecordFailure("p", now)
+
+	reopened, err := Open(path, 1, time.Hour)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if _, ok, _ := reopened.Allow("p", now); ok {
+		t.Fatalf("circuit state was not persisted")
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	s, _ := Open(path, 1, time.Minute)
+	now := time.Now()
+	s.RecordFailure("p", now)
+
+	state, ok, err := s.Allow("p", now.Add(2*time.Minute))
+	if err != nil || !ok || state != StateHalfOpen {
+		t.Fatalf("expected half-open trial after cool-down, got %s %v %v", state, ok, err)
+	}
+
+	// A failed trial reopens the circuit for another cool-down.
+	if state, _ := s.RecordFailure("p", now.Add(2*time.Minute)); state != StateOpen {
+		t.Fatalf("failed trial left state %s, want open", state)
+	}
+	if _, ok, _ := s.Allow("p", now.Add(150*time.Second)); ok {
+		t.Fatalf("circuit should be open again after failed trial")
+	}
+
+	// A successful trial closes it.
+	s.Allow("p", now.Add(5*time.Minute))
+	s.RecordSuccess("p", now.Add(5*time.Minute), time.Minute)
+	if r := s.Records()["p"]; r.State != StateClosed || r.ConsecutiveFailures != 0 {
+		t.Fatalf("unexpected record after successful trial: %+v", r)
+	}
+}
+
+func TestBreakerHalfOpenAllowsOneTrial(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	s, _ := Open(path, 1, time.Minute)
+	now := time.Now()
+	s.RecordFailure("p", now)
+
+	later := now.Add(2 * time.Minute)
+	if _, ok, _ := s.Allow("p", later); !ok {
+		t.Fatalf("first run after cool-down was not allowed as trial")
+	}
+	if state, ok, _ := s.Allow("p", later); ok || state != StateHalfOpen {
+		t.Fatalf("second run during trial allowed=%v state=%s, want skipped half-open", ok, state)
+	}
+
+	// A trial that ends without a result frees the slot.
+	s.Release("p")
+	if _, ok, _ := s.Allow("p", later); !ok {
+		t.Fatalf("run after released trial was not allowed")
+	}
+	s.RecordSuccess("p", later, time.Minute)
+	if _, ok, _ := s.Allow("p", later); !ok {
+		t.Fatalf("closed circuit blocked run")
+	}
+}
+
+func TestBreakerReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	s, _ := Open(path, 1, time.Hour)
+	now := time.Now()
+	s.RecordFailure("a", now)
+	s.RecordFailure("b", now)
+
+	if err := s.Reset("a"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if _, ok, _ := s.Allow("a", now); !ok {
+		t.Fatalf("reset circuit still open")
+	}
+	if _, ok, _ := s.Allow("b", now); ok {
+		t.Fatalf("circuit of b should still be open")
+	}
+
+	if err := s.Reset(); err != nil {
+		t.Fatalf("reset all: %v", err)
+	}
+	if names := s.Names(); len(names) != 0 {
+		t.Fatalf("expected empty store, got %v", names)
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	s, _ := Open(path, 0, time.Hour)
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		s.RecordFailure("p", now)
+	}
+	if _, ok, _ := s.Allow("p", now); !ok {
+		t.Fatalf("disabled breaker blocked run")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("disabled breaker wrote %s", path)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	os.WriteFile(path, []byte("{"), 0644)
+	if _, err := Open(path, 1, time.Hour); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
+
+func TestLastDuration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	s, _ := Open(path, 0, time.Hour)
+	if d := s.LastDuration("p"); d != 0 {
+		t.Fatalf("unknown pipeline has duration %s", d)
+	}
+	s.RecordSuccess("p", time.Now(), 90*time.Second)
+
+	reopened, err := Open(path, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if d := reopened.LastDuration("p"); d != 90*time.Second {
+		t.Fatalf("LastDuration = %s, want 1m30s", d)
+	}
+}
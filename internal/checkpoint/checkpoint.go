@@ -0,0 +1,94 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"sling-sync-wrapper/internal/fsutil"
+)
+
+// Entry is the recorded outcome of one pipeline.
+type Entry struct {
+	Status     string    `json:"status"`
+	JobID      string    `json:"sync_job_id"`
+	TraceID    string    `json:"trace_id,omitempty"`
+	SpanID     string    `json:"span_id,omitempty"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Succeeded reports whether the pipeline does not need to run again.
+func (e Entry) Succeeded() bool {
+	return e.Status == "success"
+}
+
+// Checkpoint records the outcome of each pipeline of a run as soon as it is
+// known, so that an interrupted or partially failed run can be resumed. It
+// is safe for concurrent use.
+type Checkpoint struct {
+	mu   sync.Mutex
+	path string
+	data checkpointData
+}
+
+type checkpointData struct {
+	StartedAt time.Time        `json:"started_at"`
+	Pipelines map[string]Entry `json:"pipelines"`
+}
+
+// New returns an empty checkpoint for a run started at startedAt that is
+// saved to path.
+func New(path string, startedAt time.Time) *Checkpoint {
+	return &Checkpoint{path: path, data: checkpointData{StartedAt: startedAt, Pipelines: map[string]Entry{}}}
+}
+
+// Load reads the checkpoint at path. The error wraps fs.ErrNotExist if there
+// is none.
+func Load(path string) (*Checkpoint, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+	c := &Checkpoint{path: path}
+	if err := json.Unmarshal(raw, &c.data); err != nil {
+		return nil, fmt.Errorf("parse checkpoint %s: %w", path, err)
+	}
+	if c.data.Pipelines == nil {
+		c.data.Pipelines = map[string]Entry{}
+	}
+	return c, nil
+}
+
+// StartedAt returns the start time of the run the checkpoint belongs to.
+func (c *Checkpoint) StartedAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.data.StartedAt
+}
+
+// Entry returns the recorded outcome of pipeline.
+func (c *Checkpoint) Entry(pipeline string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.data.Pipelines[pipeline]
+	return e, ok
+}
+
+// Record stores the outcome of pipeline and saves the checkpoint.
+func (c *Checkpoint) Record(pipeline string, e Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data.Pipelines[pipeline] = e
+	data, err := json.MarshalIndent(c.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode checkpoint: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(c.path, data); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return nil
+}
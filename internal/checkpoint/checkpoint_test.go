@@ -0,0 +1,54 @@
+package checkpoint
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := New(path, started)
+	if err := c.Record("dims", Entry{Status: "success", JobID: "j1", TraceID: "t1", SpanID: "s1"}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err := c.Record("facts", Entry{Status: "failed", JobID: "j2", Error: "boom"}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !loaded.StartedAt().Equal(started) {
+		t.Fatalf("StartedAt = %s, want %s", loaded.StartedAt(), started)
+	}
+	if e, ok := loaded.Entry("dims"); !ok || !e.Succeeded() || e.SpanID != "s1" {
+		t.Fatalf("unexpected dims entry: %+v", e)
+	}
+	if e, ok := loaded.Entry("facts"); !ok || e.Succeeded() {
+		t.Fatalf("unexpected facts entry: %+v", e)
+	}
+	if _, ok := loaded.Entry("lookups"); ok {
+		t.Fatalf("unexpected entry for pipeline that never ran")
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "checkpoint.json"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	os.WriteFile(path, []byte("{"), 0644)
+	if _, err := Load(path); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
@@ -2,10 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,29 +18,141 @@ type Config struct {
 	PipelineDir      string
 	StateLocation    string
 	OTELEndpoint     string
-	SyncMode         string
-	MaxRetries       int
-	BackoffBase      time.Duration
-	SlingBinary      string
-	SlingTimeout     time.Duration
+	// OTEL* hold the OTLP exporter settings of the OpenTelemetry
+	// specification's OTEL_EXPORTER_OTLP_* variables.
+	OTELProtocol          string
+	OTELHeaders           string
+	OTELCertificate       string
+	OTELClientCertificate string
+	OTELClientKey         string
+	OTELCompression       string
+	OTELTimeout           time.Duration
+	// TracesSampler and TracesSamplerArg select the trace sampler like
+	// OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG.
+	TracesSampler    string
+	TracesSamplerArg string
+	// TelemetryExporter is otlp, stdout, or file to write telemetry to
+	// TelemetryFile for local debugging.
+	TelemetryExporter string
+	TelemetryFile     string
+	SyncMode          string
+	MaxRetries        int
+	BackoffBase       time.Duration
+	BackoffMax        time.Duration
+	BackoffJitter     string
+	RetryBudget       time.Duration
+	RunRetryBudget    time.Duration
+	SlingBinary       string
+	SlingTimeout      time.Duration
+	Concurrency       int
+	ShutdownGrace     time.Duration
+	ErrorRulesFile    string
+	DataDir           string
+	BreakerThreshold  int
+	BreakerCooldown   time.Duration
+	RunDeadline       time.Duration
+	ReportFile        string
+	ReportFormat      string
+	TerminationLog    string
+	PushgatewayURL    string
+	LockBackend       string
+	LockDSN           string
+	LockTTL           time.Duration
+	WaitForLock       time.Duration
+	Schedule          string
+	ScheduleJitter    time.Duration
+	CatchUp           string
+	HTTPAddr          string
+	// TraceParent and TraceState are the W3C trace context of the caller
+	// whose trace a run continues.
+	TraceParent string
+	TraceState  string
+	// RequireTelemetry fails the run if telemetry cannot be set up instead of
+	// running without it.
+	RequireTelemetry bool
+	// SpanSpoolMaxBytes caps the spool of spans that could not be delivered
+	// to the collector; 0 disables the spool.
+	SpanSpoolMaxBytes int
+	// Resume is set by run --resume to re-run only the pipelines that did
+	// not succeed in the previous run.
+	Resume bool
 }
 
 // FromEnv constructs a Config from environment variables.
 func FromEnv() Config {
 	return Config{
-		MissionClusterID: getEnv("MISSION_CLUSTER_ID", "unknown-cluster"),
-		PipelineFile:     os.Getenv("SLING_CONFIG"),
-		PipelineDir:      os.Getenv("PIPELINE_DIR"),
-		StateLocation:    getEnv("SLING_STATE", "file://./sling_state.json"),
-		OTELEndpoint:     getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4317"),
-		SyncMode:         getEnv("SYNC_MODE", "normal"),
-		MaxRetries:       getEnvInt("SYNC_MAX_RETRIES", 3),
-		BackoffBase:      getEnvDuration("SYNC_BACKOFF_BASE", 5*time.Second),
-		SlingBinary:      getEnv("SLING_BIN", "sling"),
-		SlingTimeout:     getEnvDuration("SLING_TIMEOUT", 30*time.Minute),
+		MissionClusterID:      getEnv("MISSION_CLUSTER_ID", "unknown-cluster"),
+		PipelineFile:          os.Getenv("SLING_CONFIG"),
+		PipelineDir:           os.Getenv("PIPELINE_DIR"),
+		StateLocation:         getEnv("SLING_STATE", "file://./sling_state.json"),
+		OTELEndpoint:          os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		OTELProtocol:          getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc"),
+		OTELHeaders:           os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"),
+		OTELCertificate:       os.Getenv("OTEL_EXPORTER_OTLP_CERTIFICATE"),
+		OTELClientCertificate: os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE"),
+		OTELClientKey:         os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_KEY"),
+		OTELCompression:       getEnv("OTEL_EXPORTER_OTLP_COMPRESSION", "none"),
+		OTELTimeout:           getEnvMillis("OTEL_EXPORTER_OTLP_TIMEOUT", 10*time.Second),
+		TracesSampler:         getEnv("OTEL_TRACES_SAMPLER", "parentbased_always_on"),
+		TracesSamplerArg:      os.Getenv("OTEL_TRACES_SAMPLER_ARG"),
+		TelemetryExporter:     getEnv("SYNC_TELEMETRY_EXPORTER", "otlp"),
+		TelemetryFile:         os.Getenv("SYNC_TELEMETRY_FILE"),
+		SyncMode:              getEnv("SYNC_MODE", "normal"),
+		MaxRetries:            getEnvInt("SYNC_MAX_RETRIES", 3),
+		BackoffBase:           getEnvDuration("SYNC_BACKOFF_BASE", 5*time.Second),
+		BackoffMax:            getEnvDuration("SYNC_BACKOFF_MAX", 5*time.Minute),
+		BackoffJitter:         getEnv("SYNC_BACKOFF_JITTER", "none"),
+		RetryBudget:           getEnvDuration("SYNC_RETRY_BUDGET", 0),
+		RunRetryBudget:        getEnvDuration("SYNC_RUN_RETRY_BUDGET", 0),
+		SlingBinary:           getEnv("SLING_BIN", "sling"),
+		SlingTimeout:          getEnvDuration("SLING_TIMEOUT", 30*time.Minute),
+		Concurrency:           getEnvInt("SYNC_CONCURRENCY", 1),
+		ShutdownGrace:         getEnvDuration("SYNC_SHUTDOWN_GRACE", 20*time.Second),
+		ErrorRulesFile:        os.Getenv("SYNC_ERROR_RULES"),
+		DataDir:               os.Getenv("SYNC_DATA_DIR"),
+		BreakerThreshold:      getEnvInt("SYNC_BREAKER_THRESHOLD", 5),
+		BreakerCooldown:       getEnvDuration("SYNC_BREAKER_COOLDOWN", 30*time.Minute),
+		RunDeadline:           getEnvDuration("SYNC_RUN_DEADLINE", 0),
+		ReportFile:            os.Getenv("SYNC_REPORT"),
+		ReportFormat:          getEnv("SYNC_REPORT_FORMAT", "json"),
+		TerminationLog:        os.Getenv("SYNC_TERMINATION_LOG"),
+		PushgatewayURL:        os.Getenv("SYNC_PUSHGATEWAY_URL"),
+		LockBackend:           getEnv("SYNC_LOCK_BACKEND", "file"),
+		LockDSN:               os.Getenv("SYNC_LOCK_DSN"),
+		LockTTL:               getEnvDuration("SYNC_LOCK_TTL", 2*time.Minute),
+		WaitForLock:           getEnvDuration("SYNC_WAIT_FOR_LOCK", 0),
+		Schedule:              os.Getenv("SYNC_SCHEDULE"),
+		ScheduleJitter:        getEnvDuration("SYNC_SCHEDULE_JITTER", 0),
+		CatchUp:               getEnv("SYNC_CATCHUP", "skip"),
+		HTTPAddr:              os.Getenv("SYNC_HTTP_ADDR"),
+		TraceParent:           os.Getenv("TRACEPARENT"),
+		TraceState:            os.Getenv("TRACESTATE"),
+		RequireTelemetry:      getEnvBool("SYNC_REQUIRE_TELEMETRY", false),
+		SpanSpoolMaxBytes:     getEnvInt("SYNC_SPAN_SPOOL_MAX_BYTES", 64<<20),
 	}
 }
 
+// DataDir returns the directory where the wrapper keeps its own files between
+// runs. Unless set explicitly it is the directory of a file-based state
+// location, or the working directory otherwise.
+func DataDir(cfg Config) string {
+	if cfg.DataDir != "" {
+		return cfg.DataDir
+	}
+	u, err := url.Parse(cfg.StateLocation)
+	if err != nil || (u.Scheme != "" && u.Scheme != "file") {
+		return "."
+	}
+	p := u.Host + u.Path
+	if p == "" {
+		p = u.Opaque
+	}
+	if p == "" {
+		return "."
+	}
+	return filepath.Dir(p)
+}
+
 // Pipelines returns a list of pipeline files to run.
 func Pipelines(cfg Config) ([]string, error) {
 	if cfg.PipelineDir != "" && cfg.PipelineFile != "" {
@@ -51,6 +165,14 @@ func Pipelines(cfg Config) ([]string, error) {
 		if err != nil {
 			return nil, fmt.Errorf("find pipeline files: %w", err)
 		}
+		// Sidecar manifests share the .yaml extension but are not pipelines.
+		pipelines := files[:0]
+		for _, f := range files {
+			if !strings.HasSuffix(f, sidecarSuffix) {
+				pipelines = append(pipelines, f)
+			}
+		}
+		files = pipelines
 		sort.Strings(files)
 		if len(files) > 0 {
 			return files, nil
@@ -78,6 +200,26 @@ func getEnvInt(key string, fallback int) int {
 	return fallback
 }
 
+func getEnvBool(key string, fallback bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+// getEnvMillis reads a duration given in milliseconds, the unit of the
+// OpenTelemetry timeout variables.
+func getEnvMillis(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return fallback
+}
+
 func getEnvDuration(key string, fallback time.Duration) time.Duration {
 	if v := os.Getenv(key); v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
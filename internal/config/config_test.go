@@ -17,8 +17,11 @@ func TestFromEnvDefaults(t *testing.T) {
 	if cfg.StateLocation != "file://./sling_state.json" {
 		t.Errorf("unexpected default state location: %s", cfg.StateLocation)
 	}
-	if cfg.OTELEndpoint != "localhost:4317" {
-		t.Errorf("unexpected default OTEL endpoint: %s", cfg.OTELEndpoint)
+	if cfg.OTELEndpoint != "" || cfg.OTELProtocol != "grpc" || cfg.OTELCompression != "none" || cfg.OTELTimeout != 10*time.Second {
+		t.Errorf("unexpected default OTLP settings: %q/%q/%q/%s", cfg.OTELEndpoint, cfg.OTELProtocol, cfg.OTELCompression, cfg.OTELTimeout)
+	}
+	if cfg.TracesSampler != "parentbased_always_on" || cfg.TelemetryExporter != "otlp" || cfg.TelemetryFile != "" {
+		t.Errorf("unexpected default telemetry settings: %q/%q/%q", cfg.TracesSampler, cfg.TelemetryExporter, cfg.TelemetryFile)
 	}
 	if cfg.SyncMode != "normal" {
 		t.Errorf("unexpected default sync mode: %s", cfg.SyncMode)
@@ -35,6 +38,36 @@ func TestFromEnvDefaults(t *testing.T) {
 	if cfg.SlingTimeout != 30*time.Minute {
 		t.Errorf("unexpected default sling timeout: %s", cfg.SlingTimeout)
 	}
+	if cfg.Concurrency != 1 {
+		t.Errorf("unexpected default concurrency: %d", cfg.Concurrency)
+	}
+	if cfg.ShutdownGrace != 20*time.Second {
+		t.Errorf("unexpected default shutdown grace: %s", cfg.ShutdownGrace)
+	}
+	if cfg.BackoffMax != 5*time.Minute || cfg.BackoffJitter != "none" {
+		t.Errorf("unexpected default backoff cap/jitter: %s/%s", cfg.BackoffMax, cfg.BackoffJitter)
+	}
+	if cfg.RetryBudget != 0 || cfg.RunRetryBudget != 0 {
+		t.Errorf("unexpected default retry budgets: %s/%s", cfg.RetryBudget, cfg.RunRetryBudget)
+	}
+	if cfg.BreakerThreshold != 5 || cfg.BreakerCooldown != 30*time.Minute {
+		t.Errorf("unexpected default breaker settings: %d/%s", cfg.BreakerThreshold, cfg.BreakerCooldown)
+	}
+	if cfg.RunDeadline != 0 {
+		t.Errorf("unexpected default run deadline: %s", cfg.RunDeadline)
+	}
+	if cfg.ReportFile != "" || cfg.ReportFormat != "json" || cfg.TerminationLog != "" || cfg.PushgatewayURL != "" {
+		t.Errorf("unexpected default report settings: %q/%q/%q", cfg.ReportFile, cfg.ReportFormat, cfg.TerminationLog)
+	}
+	if cfg.LockBackend != "file" || cfg.LockDSN != "" || cfg.LockTTL != 2*time.Minute || cfg.WaitForLock != 0 {
+		t.Errorf("unexpected default lock settings: %q/%q/%s/%s", cfg.LockBackend, cfg.LockDSN, cfg.LockTTL, cfg.WaitForLock)
+	}
+	if cfg.Schedule != "" || cfg.ScheduleJitter != 0 || cfg.CatchUp != "skip" || cfg.HTTPAddr != "" {
+		t.Errorf("unexpected default daemon settings: %q/%s/%q/%q", cfg.Schedule, cfg.ScheduleJitter, cfg.CatchUp, cfg.HTTPAddr)
+	}
+	if cfg.RequireTelemetry || cfg.SpanSpoolMaxBytes != 64<<20 {
+		t.Errorf("unexpected default telemetry settings: %t/%d", cfg.RequireTelemetry, cfg.SpanSpoolMaxBytes)
+	}
 }
 
 func TestFromEnvOverrides(t *testing.T) {
@@ -42,11 +75,48 @@ func TestFromEnvOverrides(t *testing.T) {
 	t.Setenv("SLING_CONFIG", "pipeline.yaml")
 	t.Setenv("SLING_STATE", "state.json")
 	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "otel:4317")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/protobuf")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "authorization=Bearer%20token")
+	t.Setenv("OTEL_EXPORTER_OTLP_CERTIFICATE", "/certs/ca.pem")
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE", "/certs/client.pem")
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_KEY", "/certs/client-key.pem")
+	t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "gzip")
+	t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "2500")
+	t.Setenv("OTEL_TRACES_SAMPLER", "traceidratio")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.25")
+	t.Setenv("SYNC_TELEMETRY_EXPORTER", "file")
+	t.Setenv("SYNC_TELEMETRY_FILE", "/tmp/telemetry.jsonl")
 	t.Setenv("SYNC_MODE", "backfill")
 	t.Setenv("SYNC_MAX_RETRIES", "5")
 	t.Setenv("SYNC_BACKOFF_BASE", "2s")
 	t.Setenv("SLING_BIN", "/usr/local/bin/sling")
 	t.Setenv("SLING_TIMEOUT", "10s")
+	t.Setenv("SYNC_CONCURRENCY", "4")
+	t.Setenv("SYNC_SHUTDOWN_GRACE", "5s")
+	t.Setenv("SYNC_BACKOFF_MAX", "1m")
+	t.Setenv("SYNC_BACKOFF_JITTER", "full")
+	t.Setenv("SYNC_RETRY_BUDGET", "10m")
+	t.Setenv("SYNC_RUN_RETRY_BUDGET", "20m")
+	t.Setenv("SYNC_DATA_DIR", "/var/lib/sling")
+	t.Setenv("SYNC_BREAKER_THRESHOLD", "2")
+	t.Setenv("SYNC_BREAKER_COOLDOWN", "1h")
+	t.Setenv("SYNC_RUN_DEADLINE", "4m30s")
+	t.Setenv("SYNC_REPORT", "report.xml")
+	t.Setenv("SYNC_REPORT_FORMAT", "junit")
+	t.Setenv("SYNC_TERMINATION_LOG", "/dev/termination-log")
+	t.Setenv("SYNC_PUSHGATEWAY_URL", "http://pushgateway:9091")
+	t.Setenv("SYNC_LOCK_BACKEND", "sql")
+	t.Setenv("SYNC_LOCK_DSN", "postgres://db/locks")
+	t.Setenv("SYNC_LOCK_TTL", "30s")
+	t.Setenv("SYNC_WAIT_FOR_LOCK", "1m")
+	t.Setenv("SYNC_SCHEDULE", "*/5 * * * *")
+	t.Setenv("SYNC_SCHEDULE_JITTER", "30s")
+	t.Setenv("SYNC_CATCHUP", "once")
+	t.Setenv("SYNC_HTTP_ADDR", ":8080")
+	t.Setenv("TRACEPARENT", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	t.Setenv("TRACESTATE", "vendor=value")
+	t.Setenv("SYNC_REQUIRE_TELEMETRY", "true")
+	t.Setenv("SYNC_SPAN_SPOOL_MAX_BYTES", "1048576")
 
 	cfg := FromEnv()
 	if cfg.MissionClusterID != "mc1" || cfg.PipelineFile != "pipeline.yaml" {
@@ -61,12 +131,77 @@ func TestFromEnvOverrides(t *testing.T) {
 	if cfg.OTELEndpoint != "otel:4317" {
 		t.Errorf("unexpected otel endpoint: %s", cfg.OTELEndpoint)
 	}
+	if cfg.OTELProtocol != "http/protobuf" || cfg.OTELHeaders != "authorization=Bearer%20token" || cfg.OTELCompression != "gzip" ||
+		cfg.OTELTimeout != 2500*time.Millisecond {
+		t.Errorf("unexpected OTLP settings: %q/%q/%q/%s", cfg.OTELProtocol, cfg.OTELHeaders, cfg.OTELCompression, cfg.OTELTimeout)
+	}
+	if cfg.OTELCertificate != "/certs/ca.pem" || cfg.OTELClientCertificate != "/certs/client.pem" || cfg.OTELClientKey != "/certs/client-key.pem" {
+		t.Errorf("unexpected OTLP certificates: %q/%q/%q", cfg.OTELCertificate, cfg.OTELClientCertificate, cfg.OTELClientKey)
+	}
+	if cfg.TracesSampler != "traceidratio" || cfg.TracesSamplerArg != "0.25" || cfg.TelemetryExporter != "file" || cfg.TelemetryFile != "/tmp/telemetry.jsonl" {
+		t.Errorf("unexpected telemetry settings: %q/%q/%q/%q", cfg.TracesSampler, cfg.TracesSamplerArg, cfg.TelemetryExporter, cfg.TelemetryFile)
+	}
 	if cfg.SlingBinary != "/usr/local/bin/sling" {
 		t.Errorf("unexpected sling binary: %s", cfg.SlingBinary)
 	}
 	if cfg.SlingTimeout != 10*time.Second {
 		t.Errorf("unexpected sling timeout: %s", cfg.SlingTimeout)
 	}
+	if cfg.Concurrency != 4 {
+		t.Errorf("unexpected concurrency: %d", cfg.Concurrency)
+	}
+	if cfg.ShutdownGrace != 5*time.Second {
+		t.Errorf("unexpected shutdown grace: %s", cfg.ShutdownGrace)
+	}
+	if cfg.BackoffMax != time.Minute || cfg.BackoffJitter != "full" {
+		t.Errorf("unexpected backoff cap/jitter: %s/%s", cfg.BackoffMax, cfg.BackoffJitter)
+	}
+	if cfg.RetryBudget != 10*time.Minute || cfg.RunRetryBudget != 20*time.Minute {
+		t.Errorf("unexpected retry budgets: %s/%s", cfg.RetryBudget, cfg.RunRetryBudget)
+	}
+	if cfg.DataDir != "/var/lib/sling" {
+		t.Errorf("unexpected data dir: %s", cfg.DataDir)
+	}
+	if cfg.BreakerThreshold != 2 || cfg.BreakerCooldown != time.Hour {
+		t.Errorf("unexpected breaker settings: %d/%s", cfg.BreakerThreshold, cfg.BreakerCooldown)
+	}
+	if cfg.RunDeadline != 4*time.Minute+30*time.Second {
+		t.Errorf("unexpected run deadline: %s", cfg.RunDeadline)
+	}
+	if cfg.ReportFile != "report.xml" || cfg.ReportFormat != "junit" || cfg.TerminationLog != "/dev/termination-log" ||
+		cfg.PushgatewayURL != "http://pushgateway:9091" {
+		t.Errorf("unexpected report settings: %q/%q/%q", cfg.ReportFile, cfg.ReportFormat, cfg.TerminationLog)
+	}
+	if cfg.TraceParent != "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01" || cfg.TraceState != "vendor=value" {
+		t.Errorf("unexpected trace context: %q/%q", cfg.TraceParent, cfg.TraceState)
+	}
+	if !cfg.RequireTelemetry || cfg.SpanSpoolMaxBytes != 1<<20 {
+		t.Errorf("unexpected telemetry settings: %t/%d", cfg.RequireTelemetry, cfg.SpanSpoolMaxBytes)
+	}
+	if cfg.LockBackend != "sql" || cfg.LockDSN != "postgres://db/locks" || cfg.LockTTL != 30*time.Second || cfg.WaitForLock != time.Minute {
+		t.Errorf("unexpected lock settings: %q/%q/%s/%s", cfg.LockBackend, cfg.LockDSN, cfg.LockTTL, cfg.WaitForLock)
+	}
+	if cfg.Schedule != "*/5 * * * *" || cfg.ScheduleJitter != 30*time.Second || cfg.CatchUp != "once" || cfg.HTTPAddr != ":8080" {
+		t.Errorf("unexpected daemon settings: %q/%s/%q/%q", cfg.Schedule, cfg.ScheduleJitter, cfg.CatchUp, cfg.HTTPAddr)
+	}
+}
+
+func TestDataDir(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{DataDir: "/data", StateLocation: "file:///state/sling.json"}, "/data"},
+		{Config{StateLocation: "file:///state/sling.json"}, "/state"},
+		{Config{StateLocation: "file://./sling_state.json"}, "."},
+		{Config{StateLocation: "state/sling.json"}, "state"},
+		{Config{StateLocation: "greptimedb://greptimedb:4001/sling_state"}, "."},
+	}
+	for _, tt := range tests {
+		if got := DataDir(tt.cfg); got != tt.want {
+			t.Errorf("DataDir(%+v) = %q, want %q", tt.cfg, got, tt.want)
+		}
+	}
 }
 
 func TestPipelinesFile(t *testing.T) {
@@ -172,6 +307,44 @@ func TestGetEnvDuration(t *testing.T) {
 	}
 }
 
+func TestGetEnvBool(t *testing.T) {
+	const key = "TEST_ENV_BOOL"
+
+	os.Unsetenv(key)
+	if got := getEnvBool(key, true); !got {
+		t.Fatalf("expected fallback true, got %t", got)
+	}
+
+	t.Setenv(key, "false")
+	if got := getEnvBool(key, true); got {
+		t.Fatalf("expected false, got %t", got)
+	}
+
+	t.Setenv(key, "notabool")
+	if got := getEnvBool(key, true); !got {
+		t.Fatalf("expected fallback true on invalid bool, got %t", got)
+	}
+}
+
+func TestGetEnvMillis(t *testing.T) {
+	const key = "TEST_ENV_MILLIS"
+
+	os.Unsetenv(key)
+	if got := getEnvMillis(key, time.Second); got != time.Second {
+		t.Fatalf("expected fallback 1s, got %s", got)
+	}
+
+	t.Setenv(key, "1500")
+	if got := getEnvMillis(key, time.Second); got != 1500*time.Millisecond {
+		t.Fatalf("expected 1.5s, got %s", got)
+	}
+
+	t.Setenv(key, "2s")
+	if got := getEnvMillis(key, time.Second); got != time.Second {
+		t.Fatalf("expected fallback 1s on invalid milliseconds, got %s", got)
+	}
+}
+
 func TestFromEnvInvalidDuration(t *testing.T) {
 	t.Setenv("SYNC_BACKOFF_BASE", "invalid")
 	t.Setenv("SLING_TIMEOUT", "bad")
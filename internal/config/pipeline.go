@@ -0,0 +1,191 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"sling-sync-wrapper/internal/schedule"
+)
+
+// sidecarSuffix is the file name suffix of wrapper sidecar manifests. A
+// pipeline "dim_users.yaml" may be accompanied by "dim_users.wrapper.yaml".
+const sidecarSuffix = ".wrapper.yaml"
+
+// Pipeline describes a pipeline file together with the wrapper options
+// declared for it.
+type Pipeline struct {
+	Name    string
+	Path    string
+	Options PipelineOptions
+}
+
+// PipelineOptions holds wrapper-specific settings for a pipeline. They are
+// read from a sidecar manifest if one exists, otherwise from the top-level
+// `wrapper` section of the pipeline file itself.
+type PipelineOptions struct {
+	// DependsOn lists the names of pipelines that must succeed before this
+	// pipeline is started.
+	DependsOn []string `yaml:"depends_on"`
+	// ExpectedDuration is how long a run of the pipeline is expected to take.
+	// It is used to decide whether the pipeline still fits into the run
+	// deadline and defaults to the duration of the last successful run.
+	ExpectedDuration time.Duration `yaml:"expected_duration"`
+	// Schedule runs the pipeline on its own schedule in daemon mode instead
+	// of the global SYNC_SCHEDULE. It takes a cron expression or an interval.
+	Schedule string `yaml:"schedule"`
+
+	// The following settings override the global configuration for this
+	// pipeline. Nil or empty values keep the global setting.
+
+	// Timeout overrides SLING_TIMEOUT. Zero disables the timeout.
+	Timeout *time.Duration `yaml:"timeout"`
+	// MaxRetries overrides SYNC_MAX_RETRIES.
+	MaxRetries *int `yaml:"max_retries"`
+	// BackoffBase overrides SYNC_BACKOFF_BASE.
+	BackoffBase *time.Duration `yaml:"backoff_base"`
+	// SlingBinary overrides SLING_BIN.
+	SlingBinary string `yaml:"sling_binary"`
+}
+
+// Validate reports invalid option values.
+func (o PipelineOptions) Validate() error {
+	if o.ExpectedDuration < 0 {
+		return fmt.Errorf("expected_duration must not be negative, got %s", o.ExpectedDuration)
+	}
+	if o.Schedule != "" {
+		if _, err := schedule.Parse(o.Schedule); err != nil {
+			return err
+		}
+	}
+	if o.Timeout != nil && *o.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative, got %s", *o.Timeout)
+	}
+	if o.MaxRetries != nil && *o.MaxRetries < 1 {
+		return fmt.Errorf("max_retries must be at least 1, got %d", *o.MaxRetries)
+	}
+	if o.BackoffBase != nil && *o.BackoffBase < 0 {
+		return fmt.Errorf("backoff_base must not be negative, got %s", *o.BackoffBase)
+	}
+	return nil
+}
+
+// Apply returns cfg with the pipeline's overrides merged over it.
+func (o PipelineOptions) Apply(cfg Config) Config {
+	if o.Timeout != nil {
+		cfg.SlingTimeout = *o.Timeout
+	}
+	if o.MaxRetries != nil {
+		cfg.MaxRetries = *o.MaxRetries
+	}
+	if o.BackoffBase != nil {
+		cfg.BackoffBase = *o.BackoffBase
+	}
+	if o.SlingBinary != "" {
+		cfg.SlingBinary = o.SlingBinary
+	}
+	return cfg
+}
+
+// PipelineName returns the name used to refer to the pipeline at path, which
+// is its file name without the .yaml extension.
+func PipelineName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".yaml")
+}
+
+// SidecarPath returns the location of the sidecar manifest for the pipeline
+// at path.
+func SidecarPath(path string) string {
+	return strings.TrimSuffix(path, ".yaml") + sidecarSuffix
+}
+
+// LoadPipelines returns the configured pipelines with their wrapper options.
+func LoadPipelines(cfg Config) ([]Pipeline, error) {
+	paths, err := Pipelines(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelines := make([]Pipeline, 0, len(paths))
+	for _, path := range paths {
+		opts, err := LoadPipelineOptions(path)
+		if err != nil {
+			return nil, err
+		}
+		pipelines = append(pipelines, Pipeline{Name: PipelineName(path), Path: path, Options: opts})
+	}
+	return pipelines, nil
+}
+
+// LoadPipelineOptions reads and validates the wrapper options for the
+// pipeline at path. Unknown option keys are rejected so that typos do not
+// silently fall back to the global settings.
+func LoadPipelineOptions(path string) (PipelineOptions, error) {
+	opts, source, err := readPipelineOptions(path)
+	if err != nil {
+		return PipelineOptions{}, err
+	}
+	if err := opts.Validate(); err != nil {
+		return PipelineOptions{}, fmt.Errorf("invalid wrapper options in %s: %w", source, err)
+	}
+	return opts, nil
+}
+
+// readPipelineOptions decodes the options from the sidecar or the pipeline
+// file and returns the file they were read from.
+func readPipelineOptions(path string) (PipelineOptions, string, error) {
+	var opts PipelineOptions
+
+	sidecar := SidecarPath(path)
+	data, err := os.ReadFile(sidecar)
+	switch {
+	case err == nil:
+		if err := decodeStrict(data, &opts); err != nil {
+			return PipelineOptions{}, "", fmt.Errorf("parse sidecar %s: %w", sidecar, err)
+		}
+		return opts, sidecar, nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return PipelineOptions{}, "", fmt.Errorf("read sidecar %s: %w", sidecar, err)
+	}
+
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return PipelineOptions{}, "", fmt.Errorf("read pipeline %s: %w", path, err)
+	}
+	// The rest of the pipeline file belongs to Sling, so only the wrapper
+	// section is decoded strictly.
+	var doc struct {
+		Wrapper yaml.Node `yaml:"wrapper"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return PipelineOptions{}, "", fmt.Errorf("parse pipeline %s: %w", path, err)
+	}
+	if doc.Wrapper.IsZero() {
+		return opts, path, nil
+	}
+	section, err := yaml.Marshal(&doc.Wrapper)
+	if err != nil {
+		return PipelineOptions{}, "", fmt.Errorf("parse pipeline %s: %w", path, err)
+	}
+	if err := decodeStrict(section, &opts); err != nil {
+		return PipelineOptions{}, "", fmt.Errorf("parse pipeline %s: %w", path, err)
+	}
+	return opts, path, nil
+}
+
+func decodeStrict(data []byte, v any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
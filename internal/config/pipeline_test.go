@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPipelineName(t *testing.T) {
+	if got := PipelineName("/etc/sling/pipelines/dim_users.yaml"); got != "dim_users" {
+		t.Fatalf("PipelineName = %q, want dim_users", got)
+	}
+}
+
+func TestLoadPipelineOptionsInline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "facts.yaml")
+	content := "source:\n  type: sqlite\nwrapper:\n  depends_on: [dims, lookups]\n"
+	os.WriteFile(path, []byte(content), 0644)
+
+	opts, err := LoadPipelineOptions(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(opts.DependsOn, []string{"dims", "lookups"}) {
+		t.Fatalf("unexpected depends_on: %v", opts.DependsOn)
+	}
+}
+
+func TestLoadPipelineOptionsSidecar(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "facts.yaml")
+	os.WriteFile(path, []byte("wrapper:\n  depends_on: [inline]\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "facts.wrapper.yaml"), []byte("depends_on: [sidecar]\n"), 0644)
+
+	opts, err := LoadPipelineOptions(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(opts.DependsOn, []string{"sidecar"}) {
+		t.Fatalf("sidecar should take precedence, got %v", opts.DependsOn)
+	}
+}
+
+func TestLoadPipelineOptionsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "facts.yaml")
+	os.WriteFile(path, []byte("wrapper: [unterminated\n"), 0644)
+
+	if _, err := LoadPipelineOptions(path); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
+
+func TestLoadPipelinesSkipsSidecars(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(""), 0644)
+	os.WriteFile(filepath.Join(dir, "b.yaml"), []byte(""), 0644)
+	os.WriteFile(filepath.Join(dir, "b.wrapper.yaml"), []byte("depends_on: [a]\n"), 0644)
+
+	pipelines, err := LoadPipelines(Config{PipelineDir: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pipelines) != 2 {
+		t.Fatalf("expected 2 pipelines, got %+v", pipelines)
+	}
+	if pipelines[1].Name != "b" || !reflect.DeepEqual(pipelines[1].Options.DependsOn, []string{"a"}) {
+		t.Fatalf("unexpected pipeline: %+v", pipelines[1])
+	}
+}
+
+func TestLoadPipelineOptionsOverrides(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "telemetry.yaml")
+	content := "source: {}\nwrapper:\n  timeout: 2h\n  max_retries: 5\n  backoff_base: 30s\n  sling_binary: /opt/sling/bin/sling\n"
+	os.WriteFile(path, []byte(content), 0644)
+
+	opts, err := LoadPipelineOptions(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	global := Config{SlingTimeout: 30 * time.Minute, MaxRetries: 3, BackoffBase: 5 * time.Second, SlingBinary: "sling", Concurrency: 4}
+	got := opts.Apply(global)
+	want := Config{SlingTimeout: 2 * time.Hour, MaxRetries: 5, BackoffBase: 30 * time.Second, SlingBinary: "/opt/sling/bin/sling", Concurrency: 4}
+	if got != want {
+		t.Fatalf("Apply = %+v, want %+v", got, want)
+	}
+
+	if got := (PipelineOptions{}).Apply(global); got != global {
+		t.Fatalf("empty options changed config: %+v", got)
+	}
+}
+
+func TestLoadPipelineOptionsRejectsBadValues(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":      "timout: 2h\n",
+		"negative timeout": "timeout: -1m\n",
+		"zero retries":     "max_retries: 0\n",
+		"bare number":      "backoff_base: 30\n",
+		"bad schedule":     "schedule: every day\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "p.yaml")
+			os.WriteFile(path, []byte("source: {}\n"), 0644)
+			os.WriteFile(SidecarPath(path), []byte(content), 0644)
+			if _, err := LoadPipelineOptions(path); err == nil {
+				t.Fatalf("expected error for %q", content)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrorRule maps Sling failures whose error output matches Pattern, a regular
+// expression, to Class ("transient" or "permanent").
+type ErrorRule struct {
+	Pattern string `yaml:"pattern"`
+	Class   string `yaml:"class"`
+}
+
+// LoadErrorRules reads a YAML list of error classification rules from path.
+func LoadErrorRules(path string) ([]ErrorRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read error rules: %w", err)
+	}
+	var rules []ErrorRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse error rules %s: %w", path, err)
+	}
+	return rules, nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadErrorRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	content := "- pattern: ORA-12541\n  class: transient\n- pattern: '(?i)invalid identifier'\n  class: permanent\n"
+	os.WriteFile(path, []byte(content), 0644)
+
+	rules, err := LoadErrorRules(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ErrorRule{
+		{Pattern: "ORA-12541", Class: "transient"},
+		{Pattern: "(?i)invalid identifier", Class: "permanent"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Fatalf("expected %v, got %v", want, rules)
+	}
+}
+
+func TestLoadErrorRulesMissing(t *testing.T) {
+	if _, err := LoadErrorRules(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
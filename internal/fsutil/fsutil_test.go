@@ -0,0 +1,26 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "state.json")
+	if err := WriteFileAtomic(path, []byte("one")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := WriteFileAtomic(path, []byte("two")); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "two" {
+		t.Fatalf("content = %q, want two", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("temporary files left behind: %v", entries)
+	}
+}
@@ -0,0 +1,69 @@
+//go:build unix
+
+package lock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"syscall"
+)
+
+// FileLocker uses flock(2) on files in a directory. Locks are released by
+// the kernel when the process exits, so they need no expiry, but they only
+// exclude processes that share the directory.
+type FileLocker struct {
+	dir string
+}
+
+// NewFileLocker returns a locker keeping its lock files in dir.
+func NewFileLocker(dir string) *FileLocker {
+	return &FileLocker{dir: dir}
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// path returns the lock file for key. A hash keeps keys with the same
+// readable part apart.
+func (l *FileLocker) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := unsafeChars.ReplaceAllString(filepath.Base(key), "_")
+	return filepath.Join(l.dir, fmt.Sprintf("%s-%s.lock", name, hex.EncodeToString(sum[:6])))
+}
+
+// TryAcquire implements Locker.
+func (l *FileLocker) TryAcquire(ctx context.Context, key string) (Lease, error) {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return nil, fmt.Errorf("create lock dir: %w", err)
+	}
+	path := l.path(key)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("%w: %s", ErrHeld, key)
+		}
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	return &fileLease{f: f, lost: make(chan struct{})}, nil
+}
+
+type fileLease struct {
+	f    *os.File
+	lost chan struct{}
+}
+
+func (l *fileLease) Lost() <-chan struct{} { return l.lost }
+
+func (l *fileLease) Release(ctx context.Context) error {
+	// Closing the file drops the flock.
+	return l.f.Close()
+}
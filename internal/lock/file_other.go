@@ -0,0 +1,21 @@
+//go:build !unix
+
+package lock
+
+import (
+	"context"
+	"errors"
+)
+
+// FileLocker is only supported on Unix systems.
+type FileLocker struct{}
+
+// NewFileLocker returns a locker that always fails on this platform.
+func NewFileLocker(dir string) *FileLocker {
+	return &FileLocker{}
+}
+
+// TryAcquire implements Locker.
+func (l *FileLocker) TryAcquire(ctx context.Context, key string) (Lease, error) {
+	return nil, errors.New("file locks are not supported on this platform")
+}
@@ -0,0 +1,52 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrHeld is returned when the lock is held by someone else.
+var ErrHeld = errors.New("lock is held by another invocation")
+
+// Locker hands out exclusive locks by key.
+type Locker interface {
+	// TryAcquire takes the lock for key without waiting. It returns an
+	// error wrapping ErrHeld if another holder has it.
+	TryAcquire(ctx context.Context, key string) (Lease, error)
+}
+
+// Lease is a held lock.
+type Lease interface {
+	// Lost is closed if the lock could not be kept, e.g. because its lease
+	// could not be renewed in time.
+	Lost() <-chan struct{}
+	// Release gives up the lock.
+	Release(ctx context.Context) error
+}
+
+// pollInterval is how often Acquire retries a held lock.
+var pollInterval = time.Second
+
+// Acquire takes the lock for key, retrying for up to wait while it is held
+// by someone else. A wait of zero tries once.
+func Acquire(ctx context.Context, l Locker, key string, wait time.Duration) (Lease, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		lease, err := l.TryAcquire(ctx, key)
+		if !errors.Is(err, ErrHeld) {
+			return lease, err
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, err
+		}
+		t := time.NewTimer(min(pollInterval, remaining))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFileLockerExcludes(t *testing.T) {
+	l := NewFileLocker(t.TempDir())
+	ctx := context.Background()
+
+	lease, err := l.TryAcquire(ctx, "state.json/a.yaml")
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	if _, err := l.TryAcquire(ctx, "state.json/a.yaml"); !errors.Is(err, ErrHeld) {
+		t.Fatalf("second TryAcquire() error = %v, want ErrHeld", err)
+	}
+	other, err := l.TryAcquire(ctx, "other.json/a.yaml")
+	if err != nil {
+		t.Fatalf("TryAcquire() of other key error = %v", err)
+	}
+	defer other.Release(ctx)
+
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	again, err := l.TryAcquire(ctx, "state.json/a.yaml")
+	if err != nil {
+		t.Fatalf("TryAcquire() after release error = %v", err)
+	}
+	again.Release(ctx)
+}
+
+func TestAcquireWaits(t *testing.T) {
+	orig := pollInterval
+	pollInterval = 10 * time.Millisecond
+	defer func() { pollInterval = orig }()
+
+	l := NewFileLocker(t.TempDir())
+	ctx := context.Background()
+	lease, err := l.TryAcquire(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Acquire(ctx, l, "k", 30*time.Millisecond); !errors.Is(err, ErrHeld) {
+		t.Fatalf("Acquire() error = %v, want ErrHeld after waiting", err)
+	}
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		lease.Release(ctx)
+	}()
+	got, err := Acquire(ctx, l, "k", time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v, want lock once released", err)
+	}
+	got.Release(ctx)
+}
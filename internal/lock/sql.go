@@ -0,0 +1,150 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// SQLLocker hands out leases stored as rows of a table. A lease expires
+// after its TTL unless it is renewed, which the holder does in the
+// background every third of the TTL, so locks of crashed holders free up on
+// their own. Expiry is judged by the clocks of the holders, which must
+// therefore roughly agree.
+type SQLLocker struct {
+	db     *sql.DB
+	table  string
+	holder string
+	ttl    time.Duration
+}
+
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewSQLLocker returns a locker that keeps leases in table, creating the
+// table if it does not exist. holder identifies this process in the table.
+func NewSQLLocker(ctx context.Context, db *sql.DB, table, holder string, ttl time.Duration) (*SQLLocker, error) {
+	if !tableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid lock table name %q", table)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("lock TTL must be positive, got %s", ttl)
+	}
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+table+` (
+		lock_key VARCHAR(512) PRIMARY KEY,
+		holder VARCHAR(255) NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("create lock table: %w", err)
+	}
+	return &SQLLocker{db: db, table: table, holder: holder, ttl: ttl}, nil
+}
+
+// now is replaced in tests.
+var now = time.Now
+
+func (l *SQLLocker) expiry() int64 {
+	return now().Add(l.ttl).UnixMilli()
+}
+
+// TryAcquire implements Locker.
+func (l *SQLLocker) TryAcquire(ctx context.Context, key string) (Lease, error) {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin lock transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+l.table+` WHERE lock_key = $1 AND expires_at < $2`,
+		key, now().UnixMilli()); err != nil {
+		return nil, fmt.Errorf("expire lock: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `INSERT INTO `+l.table+` (lock_key, holder, expires_at) VALUES ($1, $2, $3) ON CONFLICT (lock_key) DO NOTHING`,
+		key, l.holder, l.expiry())
+	if err != nil {
+		return nil, fmt.Errorf("insert lock: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("insert lock: %w", err)
+	}
+	if n == 0 {
+		var holder string
+		if err := tx.QueryRowContext(ctx, `SELECT holder FROM `+l.table+` WHERE lock_key = $1`, key).Scan(&holder); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrHeld, key)
+		}
+		return nil, fmt.Errorf("%w: %s (holder %s)", ErrHeld, key, holder)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit lock: %w", err)
+	}
+
+	hbCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	lease := &sqlLease{locker: l, key: key, lost: make(chan struct{}), cancel: cancel, done: make(chan struct{})}
+	go lease.heartbeat(hbCtx)
+	return lease, nil
+}
+
+type sqlLease struct {
+	locker   *SQLLocker
+	key      string
+	lost     chan struct{}
+	lostOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func (l *sqlLease) Lost() <-chan struct{} { return l.lost }
+
+// renew extends the lease. It reports false if the lease is no longer ours.
+func (l *sqlLease) renew(ctx context.Context) (bool, error) {
+	res, err := l.locker.db.ExecContext(ctx, `UPDATE `+l.locker.table+` SET expires_at = $1 WHERE lock_key = $2 AND holder = $3 AND expires_at >= $4`,
+		l.locker.expiry(), l.key, l.locker.holder, now().UnixMilli())
+	if err != nil {
+		return true, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// heartbeat renews the lease until ctx is done. The lease is lost once a
+// renewal finds it taken over or renewals keep failing until it expires.
+func (l *sqlLease) heartbeat(ctx context.Context) {
+	defer close(l.done)
+	ticker := time.NewTicker(l.locker.ttl / 3)
+	defer ticker.Stop()
+	expires := now().Add(l.locker.ttl)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ok, err := l.renew(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case !ok:
+			l.lostOnce.Do(func() { close(l.lost) })
+			return
+		case err == nil:
+			expires = now().Add(l.locker.ttl)
+		case now().After(expires):
+			l.lostOnce.Do(func() { close(l.lost) })
+			return
+		}
+	}
+}
+
+func (l *sqlLease) Release(ctx context.Context) error {
+	l.cancel()
+	<-l.done
+	if _, err := l.locker.db.ExecContext(ctx, `DELETE FROM `+l.locker.table+` WHERE lock_key = $1 AND holder = $2`,
+		l.key, l.locker.holder); err != nil {
+		return fmt.Errorf("release lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "locks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLLockerExcludes(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	a, err := NewSQLLocker(ctx, db, "sync_locks", "a", time.Minute)
+	if err != nil {
+		t.Fatalf("NewSQLLocker() error = %v", err)
+	}
+	b, err := NewSQLLocker(ctx, db, "sync_locks", "b", time.Minute)
+	if err != nil {
+		t.Fatalf("NewSQLLocker() error = %v", err)
+	}
+
+	lease, err := a.TryAcquire(ctx, "k")
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	if _, err := b.TryAcquire(ctx, "k"); !errors.Is(err, ErrHeld) {
+		t.Fatalf("TryAcquire() by other holder error = %v, want ErrHeld", err)
+	}
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	lease, err = b.TryAcquire(ctx, "k")
+	if err != nil {
+		t.Fatalf("TryAcquire() after release error = %v", err)
+	}
+	lease.Release(ctx)
+}
+
+func TestSQLLockerExpiredLeaseIsTakenOver(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	a, _ := NewSQLLocker(ctx, db, "sync_locks", "a", time.Minute)
+	b, _ := NewSQLLocker(ctx, db, "sync_locks", "b", time.Minute)
+
+	// Simulate a holder that died without releasing its lease.
+	if _, err := db.Exec(`INSERT INTO sync_locks (lock_key, holder, expires_at) VALUES ('k', 'a', $1)`,
+		time.Now().Add(-time.Second).UnixMilli()); err != nil {
+		t.Fatal(err)
+	}
+	lease, err := b.TryAcquire(ctx, "k")
+	if err != nil {
+		t.Fatalf("TryAcquire() of expired lease error = %v", err)
+	}
+	defer lease.Release(ctx)
+	if _, err := a.TryAcquire(ctx, "k"); !errors.Is(err, ErrHeld) {
+		t.Fatalf("TryAcquire() error = %v, want ErrHeld", err)
+	}
+}
+
+func TestSQLLeaseHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	ttl := 60 * time.Millisecond
+	a, _ := NewSQLLocker(ctx, db, "sync_locks", "a", ttl)
+	b, _ := NewSQLLocker(ctx, db, "sync_locks", "b", ttl)
+
+	lease, err := a.TryAcquire(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Renewals keep the lease alive well past its TTL.
+	time.Sleep(4 * ttl)
+	if _, err := b.TryAcquire(ctx, "k"); !errors.Is(err, ErrHeld) {
+		t.Fatalf("TryAcquire() error = %v, want ErrHeld while renewed", err)
+	}
+
+	// A lease taken over by someone else is reported lost.
+	if _, err := db.Exec(`UPDATE sync_locks SET holder = 'b'`); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-lease.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lease not reported lost")
+	}
+	lease.Release(ctx)
+}
+
+func TestNewSQLLockerRejectsBadTable(t *testing.T) {
+	if _, err := NewSQLLocker(context.Background(), openDB(t), "locks; DROP TABLE x", "a", time.Minute); err == nil {
+		t.Fatal("expected error for invalid table name")
+	}
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fanoutHandler passes each record to every handler that is enabled for it.
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		out[i] = handler.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	out := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		out[i] = handler.WithGroup(name)
+	}
+	return out
+}
+
+// traceHandler adds the trace_id and span_id of the span in the context to
+// each record, so that log lines can be matched to their trace. The IDs are
+// always top-level keys, even inside a group.
+type traceHandler struct {
+	slog.Handler
+	// ungrouped is the handler before the first WithGroup call and ops
+	// replays the calls since, so that the IDs can be added outside of the
+	// groups.
+	ungrouped slog.Handler
+	ops       []func(slog.Handler) slog.Handler
+}
+
+func newTraceHandler(h slog.Handler) *traceHandler {
+	return &traceHandler{Handler: h}
+}
+
+func (h *traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return h.Handler.Handle(ctx, r)
+	}
+	ids := []slog.Attr{
+		slog.String("trace_id", sc.TraceID().String()),
+		slog.String("span_id", sc.SpanID().String()),
+	}
+	if h.ungrouped == nil {
+		r.AddAttrs(ids...)
+		return h.Handler.Handle(ctx, r)
+	}
+	handler := h.ungrouped.WithAttrs(ids)
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h *traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *traceHandler) WithGroup(name string) slog.Handler {
+	out := h
+	if h.ungrouped == nil {
+		out = &traceHandler{Handler: h.Handler, ungrouped: h.Handler}
+	}
+	return out.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *traceHandler) with(op func(slog.Handler) slog.Handler) *traceHandler {
+	out := &traceHandler{Handler: op(h.Handler), ungrouped: h.ungrouped}
+	if h.ungrouped != nil {
+		out.ops = append(slices.Clip(h.ops), op)
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// recorder keeps the records emitted to a logger provider.
+type recorder struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (r *recorder) OnEmit(ctx context.Context, rec *sdklog.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, rec.Clone())
+	return nil
+}
+
+func (r *recorder) Shutdown(context.Context) error   { return nil }
+func (r *recorder) ForceFlush(context.Context) error { return nil }
+
+func attrs(rec sdklog.Record) map[string]otellog.Value {
+	out := map[string]otellog.Value{}
+	rec.WalkAttributes(func(kv otellog.KeyValue) bool {
+		out[kv.Key] = kv.Value
+		return true
+	})
+	return out
+}
+
+func TestHandlerFansOut(t *testing.T) {
+	rec := &recorder{}
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(rec))
+	var stderr bytes.Buffer
+	logger := slog.New(NewHandler(&stderr, lp.Logger("test")))
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "run")
+	defer span.End()
+	sc := span.SpanContext()
+
+	logger.With("pipeline", "dims").WithGroup("sling").ErrorContext(ctx, "attempt failed",
+		"attempt", 2, "err", errors.New("boom"))
+	logger.Debug("dropped")
+
+	var line map[string]any
+	if err := json.Unmarshal(stderr.Bytes(), &line); err != nil {
+		t.Fatalf("stderr is not a single JSON line: %v\n%s", err, stderr.String())
+	}
+	if line["trace_id"] != sc.TraceID().String() || line["pipeline"] != "dims" {
+		t.Errorf("stderr line = %v, want trace_id %s and pipeline", line, sc.TraceID())
+	}
+	if line["span_id"] != sc.SpanID().String() {
+		t.Errorf("stderr span_id = %v, want %s", line["span_id"], sc.SpanID())
+	}
+
+	if len(rec.records) != 1 {
+		t.Fatalf("exported %d records, want 1", len(rec.records))
+	}
+	got := rec.records[0]
+	if got.Body().AsString() != "attempt failed" || got.Severity() != otellog.SeverityError {
+		t.Errorf("record = %q at %v, want error", got.Body().AsString(), got.Severity())
+	}
+	if got.TraceID() != sc.TraceID() || got.SpanID() != sc.SpanID() {
+		t.Errorf("record trace = %s/%s, want %s/%s", got.TraceID(), got.SpanID(), sc.TraceID(), sc.SpanID())
+	}
+	a := attrs(got)
+	if a["pipeline"].AsString() != "dims" || a["sling.attempt"].AsInt64() != 2 || a["sling.err"].AsString() != "boom" {
+		t.Errorf("record attributes = %v", a)
+	}
+	if a["code.function"].AsString() == "" {
+		t.Errorf("record lacks source location: %v", a)
+	}
+}
+
+func TestHandlerWithoutSpan(t *testing.T) {
+	rec := &recorder{}
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(rec))
+	var stderr bytes.Buffer
+	slog.New(NewHandler(&stderr, lp.Logger("test"))).Info("started")
+
+	if bytes.Contains(stderr.Bytes(), []byte("trace_id")) {
+		t.Errorf("stderr line has a trace ID without a span: %s", stderr.String())
+	}
+	if len(rec.records) != 1 || rec.records[0].TraceID().IsValid() {
+		t.Errorf("records = %v, want one without trace", rec.records)
+	}
+}
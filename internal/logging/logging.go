@@ -2,14 +2,32 @@ package logging
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"os"
+
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
 )
 
-// New returns a JSON logger writing to stderr with source information.
+// loggerName is the instrumentation scope of exported log records.
+const loggerName = "sling-sync-wrapper"
+
+// New returns a JSON logger writing to stderr with source information. Records
+// are also emitted to the global OpenTelemetry logger provider, which drops
+// them until tracing.Init installs an exporting one. Records logged with a
+// context that carries a span include its trace_id and span_id.
 func New() *slog.Logger {
-	handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{AddSource: true})
-	return slog.New(handler)
+	return slog.New(NewHandler(os.Stderr, global.Logger(loggerName)))
+}
+
+// NewHandler returns a handler that writes JSON to w and emits every record
+// to logger.
+func NewHandler(w io.Writer, logger otellog.Logger) slog.Handler {
+	return fanoutHandler{
+		newTraceHandler(slog.NewJSONHandler(w, &slog.HandlerOptions{AddSource: true})),
+		newOTelHandler(logger),
+	}
 }
 
 type ctxKey struct{}
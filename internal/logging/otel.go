@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+)
+
+// otelHandler emits records to an OpenTelemetry logger. The logger takes the
+// trace and span IDs from the context passed to Handle.
+type otelHandler struct {
+	logger otellog.Logger
+	attrs  []otellog.KeyValue
+	// prefix is prepended to the keys of attributes added after WithGroup.
+	prefix string
+}
+
+func newOTelHandler(logger otellog.Logger) *otelHandler {
+	return &otelHandler{logger: logger}
+}
+
+func (h *otelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	// Export what is written to stderr, where debug records are dropped.
+	if level < slog.LevelInfo {
+		return false
+	}
+	return h.logger.Enabled(ctx, otellog.EnabledParameters{Severity: severity(level)})
+}
+
+func (h *otelHandler) Handle(ctx context.Context, r slog.Record) error {
+	var rec otellog.Record
+	rec.SetTimestamp(r.Time)
+	rec.SetSeverity(severity(r.Level))
+	rec.SetSeverityText(r.Level.String())
+	rec.SetBody(otellog.StringValue(r.Message))
+	rec.AddAttributes(h.attrs...)
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		rec.AddAttributes(
+			otellog.String("code.function", frame.Function),
+			otellog.String("code.filepath", frame.File),
+			otellog.Int("code.lineno", frame.Line),
+		)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		rec.AddAttributes(h.convert(a)...)
+		return true
+	})
+	h.logger.Emit(ctx, rec)
+	return nil
+}
+
+func (h *otelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := *h
+	out.attrs = slices.Clone(h.attrs)
+	for _, a := range attrs {
+		out.attrs = append(out.attrs, h.convert(a)...)
+	}
+	return &out
+}
+
+func (h *otelHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	out := *h
+	out.prefix = h.prefix + name + "."
+	return &out
+}
+
+// convert turns a into OpenTelemetry attributes. Groups are flattened into
+// dotted keys.
+func (h *otelHandler) convert(a slog.Attr) []otellog.KeyValue {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return nil
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		group := &otelHandler{prefix: h.prefix}
+		if a.Key != "" {
+			group.prefix += a.Key + "."
+		}
+		var out []otellog.KeyValue
+		for _, ga := range a.Value.Group() {
+			out = append(out, group.convert(ga)...)
+		}
+		return out
+	}
+	return []otellog.KeyValue{{Key: h.prefix + a.Key, Value: value(a.Value)}}
+}
+
+func value(v slog.Value) otellog.Value {
+	switch v.Kind() {
+	case slog.KindString:
+		return otellog.StringValue(v.String())
+	case slog.KindInt64:
+		return otellog.Int64Value(v.Int64())
+	case slog.KindUint64:
+		return otellog.Int64Value(int64(v.Uint64()))
+	case slog.KindFloat64:
+		return otellog.Float64Value(v.Float64())
+	case slog.KindBool:
+		return otellog.BoolValue(v.Bool())
+	case slog.KindDuration:
+		return otellog.StringValue(v.Duration().String())
+	case slog.KindTime:
+		return otellog.StringValue(v.Time().Format(time.RFC3339Nano))
+	}
+	switch x := v.Any().(type) {
+	case error:
+		return otellog.StringValue(x.Error())
+	case []byte:
+		return otellog.BytesValue(x)
+	case []string:
+		vs := make([]otellog.Value, len(x))
+		for i, s := range x {
+			vs[i] = otellog.StringValue(s)
+		}
+		return otellog.SliceValue(vs...)
+	}
+	return otellog.StringValue(fmt.Sprint(v.Any()))
+}
+
+// severity maps slog levels onto OpenTelemetry severities, which start at 1
+// for TRACE and put INFO at 9.
+func severity(level slog.Level) otellog.Severity {
+	return otellog.Severity(level + 9)
+}
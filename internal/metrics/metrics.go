@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// JobName is the Pushgateway job the metrics are pushed under.
+const JobName = "sling-sync-wrapper"
+
+// Metrics holds the Prometheus instruments of the wrapper. All methods are
+// safe for concurrent use and do nothing on a nil *Metrics.
+type Metrics struct {
+	missionClusterID string
+	// registry is pushed with the mission cluster as grouping label;
+	// labelled carries it as a label for scraping.
+	registry *prometheus.Registry
+	labelled *prometheus.Registry
+	runs     *prometheus.CounterVec
+	attempts *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	rows     *prometheus.HistogramVec
+
+	mu          sync.Mutex
+	lastSuccess map[string]time.Time
+	sinceDesc   *prometheus.Desc
+	now         func() time.Time
+}
+
+// New returns metrics labelled with missionClusterID.
+func New(missionClusterID string) *Metrics {
+	m := &Metrics{
+		missionClusterID: missionClusterID,
+		registry:         prometheus.NewRegistry(),
+		labelled:         prometheus.NewRegistry(),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sling_sync_runs_total",
+			Help: "Pipeline runs by final status.",
+		}, []string{"pipeline", "status"}),
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sling_sync_attempts_total",
+			Help: "Sling invocations by outcome.",
+		}, []string{"pipeline", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "sling_sync_run_duration_seconds",
+			Help:    "Duration of pipeline runs including retries.",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 8), // 1s to ~4.5h
+		}, []string{"pipeline"}),
+		rows: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "sling_sync_rows_synced",
+			Help:    "Rows synced per pipeline run.",
+			Buckets: prometheus.ExponentialBuckets(1, 10, 9), // 1 to 100M
+		}, []string{"pipeline"}),
+		lastSuccess: map[string]time.Time{},
+		sinceDesc: prometheus.NewDesc("sling_sync_seconds_since_last_success",
+			"Seconds since the pipeline last succeeded.", []string{"pipeline"}, nil),
+		now: time.Now,
+	}
+	collectors := []prometheus.Collector{m.runs, m.attempts, m.duration, m.rows, (*sinceCollector)(m)}
+	m.registry.MustRegister(collectors...)
+	prometheus.WrapRegistererWith(prometheus.Labels{"mission_cluster_id": missionClusterID}, m.labelled).MustRegister(collectors...)
+	return m
+}
+
+// ObserveAttempt counts one Sling invocation of pipeline.
+func (m *Metrics) ObserveAttempt(pipeline string, err error) {
+	if m == nil {
+		return
+	}
+	status := "success"
+	if err != nil {
+		status = "failed"
+	}
+	m.attempts.WithLabelValues(pipeline, status).Inc()
+}
+
+// ObserveRun records the outcome of a pipeline run. Duration and rows are
+// only observed for pipelines that ran Sling at least once.
+func (m *Metrics) ObserveRun(pipeline, status string, attempts int, duration time.Duration, rows int) {
+	if m == nil {
+		return
+	}
+	m.runs.WithLabelValues(pipeline, status).Inc()
+	if attempts > 0 {
+		m.duration.WithLabelValues(pipeline).Observe(duration.Seconds())
+		m.rows.WithLabelValues(pipeline).Observe(float64(rows))
+	}
+	if status == "success" {
+		m.SetLastSuccess(pipeline, m.now())
+	}
+}
+
+// SetLastSuccess records when pipeline last succeeded, e.g. as remembered
+// from previous runs.
+func (m *Metrics) SetLastSuccess(pipeline string, t time.Time) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t.After(m.lastSuccess[pipeline]) {
+		m.lastSuccess[pipeline] = t
+	}
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.labelled, promhttp.HandlerOpts{})
+}
+
+// Push replaces the metrics of this mission cluster on the Pushgateway at
+// url.
+func (m *Metrics) Push(ctx context.Context, url string) error {
+	if m == nil {
+		return nil
+	}
+	return push.New(url, JobName).
+		Gatherer(m.registry).
+		Grouping("mission_cluster_id", m.missionClusterID).
+		PushContext(ctx)
+}
+
+// sinceCollector computes the seconds since the last success of each
+// pipeline when metrics are gathered.
+type sinceCollector Metrics
+
+func (c *sinceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sinceDesc
+}
+
+func (c *sinceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for pipeline, t := range c.lastSuccess {
+		ch <- prometheus.MustNewConstMetric(c.sinceDesc, prometheus.GaugeValue, now.Sub(t).Seconds(), pipeline)
+	}
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveRun(t *testing.T) {
+	m := New("mc")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	m.ObserveAttempt("dims", errors.New("boom"))
+	m.ObserveAttempt("dims", nil)
+	m.ObserveRun("dims", "success", 2, 90*time.Second, 1200)
+	m.ObserveRun("facts", "skipped", 0, 0, 0)
+	m.SetLastSuccess("facts", now.Add(-time.Hour))
+	now = now.Add(30 * time.Second)
+
+	if got := testutil.ToFloat64(m.attempts.WithLabelValues("dims", "failed")); got != 1 {
+		t.Errorf("failed attempts = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.runs.WithLabelValues("facts", "skipped")); got != 1 {
+		t.Errorf("skipped runs = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.duration); got != 1 {
+		t.Errorf("duration series = %d, want 1 as skipped pipelines did not run", got)
+	}
+
+	want := `
+# HELP sling_sync_seconds_since_last_success Seconds since the pipeline last succeeded.
+# TYPE sling_sync_seconds_since_last_success gauge
+sling_sync_seconds_since_last_success{mission_cluster_id="mc",pipeline="dims"} 30
+sling_sync_seconds_since_last_success{mission_cluster_id="mc",pipeline="facts"} 3630
+`
+	if err := testutil.GatherAndCompare(m.labelled, strings.NewReader(want), "sling_sync_seconds_since_last_success"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := New("mc")
+	m.ObserveRun("dims", "failed", 3, time.Minute, 0)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `sling_sync_runs_total{mission_cluster_id="mc",pipeline="dims",status="failed"} 1`) {
+		t.Fatalf("unexpected metrics:\n%s", rec.Body)
+	}
+}
+
+func TestPush(t *testing.T) {
+	var method, path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	m := New("mc")
+	m.ObserveRun("dims", "success", 1, time.Second, 10)
+	if err := m.Push(context.Background(), srv.URL); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/sling-sync-wrapper/mission_cluster_id/mc" {
+		t.Fatalf("pushed with %s %s", method, path)
+	}
+	if !strings.Contains(body, "sling_sync_runs_total") {
+		t.Fatal("pushed body does not contain the run counter")
+	}
+
+	var nilMetrics *Metrics
+	nilMetrics.ObserveRun("dims", "success", 1, time.Second, 10)
+	if err := nilMetrics.Push(context.Background(), srv.URL); err != nil {
+		t.Fatalf("Push() on nil metrics error = %v", err)
+	}
+}
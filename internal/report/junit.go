@@ -0,0 +1,88 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// JUnit XML elements, following the schema understood by common CI systems.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit encodes r as a JUnit test suite with one test case per
+// pipeline. Pipelines that were not run are skipped; any other pipeline that
+// did not succeed is a failure.
+func writeJUnit(w io.Writer, r *Report) error {
+	suite := junitTestSuite{
+		Name:      "sling-sync-wrapper",
+		Tests:     len(r.Pipelines),
+		Time:      seconds(r.DurationSeconds),
+		Timestamp: r.StartedAt.UTC().Format(time.RFC3339),
+	}
+	for _, p := range r.Pipelines {
+		tc := junitTestCase{
+			Name:      p.Name,
+			ClassName: r.MissionClusterID,
+			Time:      seconds(p.DurationSeconds),
+			SystemOut: fmt.Sprintf("path=%s\nsync_job_id=%s\nstatus=%s\nattempts=%d\nrows_synced=%d\ntrace_id=%s\n",
+				p.Path, p.JobID, p.Status, p.Attempts, p.RowsSynced, p.TraceID),
+		}
+		switch p.Status {
+		case "success", "noop", "backfill":
+		case "skipped", "deferred", "circuit_open", "cancelled", "lock_held":
+			suite.Skipped++
+			tc.Skipped = &junitMessage{Message: fmt.Sprintf("%s: %s", p.Status, firstLine(p.Error))}
+		default:
+			// failed, deadline_exceeded, lock_lost and any status added later.
+			suite.Failures++
+			tc.Failure = &junitMessage{Message: firstLine(p.Error), Type: p.ErrorClass, Text: p.Error}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+func seconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
@@ -0,0 +1,170 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report formats.
+const (
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+// MaxSummaryBytes is the size limit Kubernetes applies to termination
+// messages.
+const MaxSummaryBytes = 4096
+
+// Report is the outcome of one wrapper invocation.
+type Report struct {
+	MissionClusterID string    `json:"mission_cluster_id"`
+	SyncMode         string    `json:"sync_mode"`
+	StartedAt        time.Time `json:"started_at"`
+	FinishedAt       time.Time `json:"finished_at"`
+	DurationSeconds  float64   `json:"duration_seconds"`
+	// Status is "success" if every pipeline succeeded and "failed"
+	// otherwise.
+	Status string `json:"status"`
+	// Error is the error the run ended with, if any.
+	Error string `json:"error,omitempty"`
+	// TraceID is the trace of the whole invocation.
+	TraceID string `json:"trace_id,omitempty"`
+	// TelemetryDegraded is set when the run went ahead without exporting
+	// traces, metrics and logs because telemetry could not be set up.
+	TelemetryDegraded bool       `json:"telemetry_degraded"`
+	Pipelines         []Pipeline `json:"pipelines"`
+}
+
+// Pipeline is the outcome of one pipeline.
+type Pipeline struct {
+	Name            string  `json:"name"`
+	Path            string  `json:"path"`
+	JobID           string  `json:"sync_job_id"`
+	Status          string  `json:"status"`
+	Attempts        int     `json:"attempts"`
+	RowsSynced      int     `json:"rows_synced"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	ErrorClass      string  `json:"error_class,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	TraceID         string  `json:"trace_id,omitempty"`
+	SpanID          string  `json:"span_id,omitempty"`
+}
+
+// ValidateFormat reports whether format is a known report format.
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatJSON, FormatJUnit:
+		return nil
+	}
+	return fmt.Errorf("unknown report format %q (want %s or %s)", format, FormatJSON, FormatJUnit)
+}
+
+// WriteFile writes r to path in the given format.
+func WriteFile(path, format string, r *Report) error {
+	if err := ValidateFormat(format); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create report: %w", err)
+	}
+	if err := Write(f, format, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+// Write encodes r to w in the given format.
+func Write(w io.Writer, format string, r *Report) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+		return nil
+	case FormatJUnit:
+		return writeJUnit(w, r)
+	}
+	return ValidateFormat(format)
+}
+
+// Counts returns the number of pipelines per status.
+func (r *Report) Counts() map[string]int {
+	counts := map[string]int{}
+	for _, p := range r.Pipelines {
+		counts[p.Status]++
+	}
+	return counts
+}
+
+// Summary returns a short human-readable description of r that fits into
+// a Kubernetes termination message.
+func (r *Report) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "sling-sync-wrapper %s: %d pipelines", r.Status, len(r.Pipelines))
+
+	counts := r.Counts()
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for i, status := range statuses {
+		sep := ", "
+		if i == 0 {
+			sep = " ("
+		}
+		fmt.Fprintf(&b, "%s%d %s", sep, counts[status], status)
+	}
+	if len(statuses) > 0 {
+		b.WriteString(")")
+	}
+	b.WriteString("\n")
+	if r.TelemetryDegraded {
+		b.WriteString("telemetry degraded: traces, metrics and logs were not exported\n")
+	}
+
+	for _, p := range r.Pipelines {
+		if p.Error == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %s", p.Name, p.Status)
+		if p.ErrorClass != "" {
+			fmt.Fprintf(&b, " [%s]", p.ErrorClass)
+		}
+		// Errors of pipelines that were not run already start with their
+		// status.
+		fmt.Fprintf(&b, ": %s\n", strings.TrimPrefix(firstLine(p.Error), p.Status+": "))
+	}
+	if len(r.Pipelines) == 0 && r.Error != "" {
+		fmt.Fprintf(&b, "%s\n", firstLine(r.Error))
+	}
+	return truncate(b.String(), MaxSummaryBytes)
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	const marker = "...\n"
+	return strings.ToValidUTF8(s[:n-len(marker)], "") + marker
+}
@@ -0,0 +1,119 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testReport() *Report {
+	return &Report{
+		MissionClusterID: "mc",
+		SyncMode:         "normal",
+		StartedAt:        time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		DurationSeconds:  12.5,
+		Status:           "failed",
+		Error:            "one or more pipelines failed",
+		Pipelines: []Pipeline{
+			{Name: "dims", Path: "dims.yaml", JobID: "j1", Status: "success", Attempts: 1, RowsSynced: 10, DurationSeconds: 2, TraceID: "abc"},
+			{Name: "facts", Path: "facts.yaml", JobID: "j2", Status: "failed", Attempts: 3, ErrorClass: "transient", Error: "execute sling: exit status 1\ndetails"},
+			{Name: "summary", Path: "summary.yaml", JobID: "j3", Status: "skipped", Error: `skipped: upstream pipeline "facts" failed`},
+		},
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := WriteFile(path, FormatJSON, testReport()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	var got Report
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(got.Pipelines) != 3 || got.Pipelines[1].ErrorClass != "transient" || got.Pipelines[0].TraceID != "abc" {
+		t.Fatalf("unexpected report: %+v", got)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJUnit, testReport()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var got junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("parse: %v\n%s", err, buf.String())
+	}
+	suite := got.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 {
+		t.Fatalf("unexpected counts: %+v", suite)
+	}
+	if f := suite.Cases[1].Failure; f == nil || f.Type != "transient" || f.Message != "execute sling: exit status 1" {
+		t.Fatalf("unexpected failure: %+v", f)
+	}
+	if suite.Cases[2].Skipped == nil {
+		t.Fatalf("summary should be skipped")
+	}
+}
+
+func TestWriteJUnitLockLost(t *testing.T) {
+	r := testReport()
+	r.Pipelines = []Pipeline{{Name: "facts", Status: "lock_lost", Error: "sling run failed: run lock lost: context canceled"}}
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJUnit, r); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var got junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("parse: %v\n%s", err, buf.String())
+	}
+	suite := got.Suites[0]
+	if suite.Failures != 1 || suite.Cases[0].Failure == nil {
+		t.Fatalf("lock_lost pipeline was not a failure: %+v", suite)
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "yaml", testReport()); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	got := testReport().Summary()
+	want := "sling-sync-wrapper failed: 3 pipelines (1 failed, 1 skipped, 1 success)\n" +
+		"facts: failed [transient]: execute sling: exit status 1\n" +
+		"summary: skipped: upstream pipeline \"facts\" failed\n"
+	if got != want {
+		t.Fatalf("Summary =\n%s\nwant\n%s", got, want)
+	}
+
+	r := &Report{Status: "failed", Error: "load pipelines: no pipeline files found"}
+	if got := r.Summary(); !strings.Contains(got, "no pipeline files found") {
+		t.Fatalf("summary without pipelines lacks error: %q", got)
+	}
+}
+
+func TestSummaryTelemetryDegraded(t *testing.T) {
+	r := testReport()
+	r.TelemetryDegraded = true
+	if got := r.Summary(); !strings.Contains(got, "telemetry degraded") {
+		t.Fatalf("summary lacks degraded telemetry: %q", got)
+	}
+}
+
+func TestSummaryTruncated(t *testing.T) {
+	r := testReport()
+	r.Pipelines[1].Error = strings.Repeat("ä", MaxSummaryBytes)
+	got := r.Summary()
+	if len(got) > MaxSummaryBytes || !strings.HasSuffix(got, "...\n") {
+		t.Fatalf("summary not truncated: %d bytes", len(got))
+	}
+}
@@ -0,0 +1,140 @@
+package retry
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Jitter selects how randomness is applied to backoff waits so that many
+// clients failing at the same time do not retry in lockstep.
+type Jitter string
+
+const (
+	// JitterNone uses the plain exponential backoff.
+	JitterNone Jitter = "none"
+	// JitterFull waits a random duration between zero and the backoff.
+	JitterFull Jitter = "full"
+	// JitterEqual waits half the backoff plus a random share of the other half.
+	JitterEqual Jitter = "equal"
+	// JitterDecorrelated waits a random duration between the base and three
+	// times the previous wait.
+	JitterDecorrelated Jitter = "decorrelated"
+)
+
+// ParseJitter validates a jitter mode name. An empty name means JitterNone.
+func ParseJitter(s string) (Jitter, error) {
+	switch j := Jitter(s); j {
+	case "":
+		return JitterNone, nil
+	case JitterNone, JitterFull, JitterEqual, JitterDecorrelated:
+		return j, nil
+	}
+	return "", fmt.Errorf("unknown jitter mode %q (want none, full, equal or decorrelated)", s)
+}
+
+// Policy computes the wait between retry attempts.
+type Policy struct {
+	// Base is the wait before the first retry.
+	Base time.Duration
+	// Max caps every wait. Zero means no cap.
+	Max time.Duration
+	// Jitter selects the randomization mode.
+	Jitter Jitter
+	// Rand returns a number in [0, 1). It defaults to math/rand/v2.Float64.
+	Rand func() float64
+}
+
+// Backoff returns the wait before retry number attempt (starting at 1). prev
+// is the previous wait and is only used by JitterDecorrelated.
+func (p Policy) Backoff(attempt int, prev time.Duration) time.Duration {
+	random := p.Rand
+	if random == nil {
+		random = rand.Float64
+	}
+
+	var wait time.Duration
+	switch p.Jitter {
+	case JitterDecorrelated:
+		if prev < p.Base {
+			prev = p.Base
+		}
+		upper := p.capped(3 * prev)
+		wait = p.Base + time.Duration(random()*float64(upper-p.Base))
+	case JitterFull:
+		wait = time.Duration(random() * float64(p.exponential(attempt)))
+	case JitterEqual:
+		half := p.exponential(attempt) / 2
+		wait = half + time.Duration(random()*float64(half))
+	default:
+		wait = p.exponential(attempt)
+	}
+	return p.capped(wait)
+}
+
+// exponential returns Base * 2^(attempt-1), capped by Max and guarded
+// against overflow.
+func (p Policy) exponential(attempt int) time.Duration {
+	wait := p.Base
+	for i := 1; i < attempt; i++ {
+		if wait > (1<<62)/2 {
+			break
+		}
+		wait *= 2
+		if p.Max > 0 && wait >= p.Max {
+			break
+		}
+	}
+	return p.capped(wait)
+}
+
+func (p Policy) capped(d time.Duration) time.Duration {
+	if p.Max > 0 && d > p.Max {
+		return p.Max
+	}
+	return d
+}
+
+// Budget is an allowance of retry time that may be shared between
+// goroutines. A nil *Budget or one with a zero limit is unlimited.
+type Budget struct {
+	mu    sync.Mutex
+	limit time.Duration
+	used  time.Duration
+}
+
+// NewBudget returns a budget allowing limit of retry time.
+func NewBudget(limit time.Duration) *Budget {
+	return &Budget{limit: limit}
+}
+
+// Allows reports whether d more retry time fits into the budget.
+func (b *Budget) Allows(d time.Duration) bool {
+	if b == nil || b.limit <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used+d <= b.limit
+}
+
+// Consume records d of retry time against the budget.
+func (b *Budget) Consume(d time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used += d
+}
+
+// Used returns the retry time consumed so far.
+func (b *Budget) Used() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
@@ -0,0 +1,104 @@
+package retry
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackoffExponential(t *testing.T) {
+	p := Policy{Base: time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i+1, 0); got != w {
+			t.Errorf("attempt %d: got %s, want %s", i+1, got, w)
+		}
+	}
+}
+
+func TestBackoffCap(t *testing.T) {
+	p := Policy{Base: time.Second, Max: 10 * time.Second}
+	if got := p.Backoff(5, 0); got != 10*time.Second {
+		t.Fatalf("expected cap of 10s, got %s", got)
+	}
+	if got := p.Backoff(200, 0); got != 10*time.Second {
+		t.Fatalf("expected cap of 10s for large attempt, got %s", got)
+	}
+}
+
+func TestBackoffNoOverflow(t *testing.T) {
+	p := Policy{Base: time.Second}
+	if got := p.Backoff(200, 0); got <= 0 {
+		t.Fatalf("backoff overflowed: %s", got)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	half := func() float64 { return 0.5 }
+	tests := []struct {
+		jitter Jitter
+		prev   time.Duration
+		want   time.Duration
+	}{
+		{JitterFull, 0, 2 * time.Second},                               // 0.5 * 4s
+		{JitterEqual, 0, 3 * time.Second},                              // 2s + 0.5 * 2s
+		{JitterDecorrelated, 0, 2 * time.Second},                       // 1s + 0.5 * (3s - 1s)
+		{JitterDecorrelated, 4 * time.Second, 6500 * time.Millisecond}, // 1s + 0.5 * (12s - 1s)
+	}
+	for _, tt := range tests {
+		p := Policy{Base: time.Second, Max: time.Minute, Jitter: tt.jitter, Rand: half}
+		if got := p.Backoff(3, tt.prev); got != tt.want {
+			t.Errorf("%s (prev %s): got %s, want %s", tt.jitter, tt.prev, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffJitterRespectsCap(t *testing.T) {
+	one := func() float64 { return 0.999 }
+	for _, j := range []Jitter{JitterFull, JitterEqual, JitterDecorrelated} {
+		p := Policy{Base: time.Second, Max: 5 * time.Second, Jitter: j, Rand: one}
+		if got := p.Backoff(10, time.Hour); got > 5*time.Second {
+			t.Errorf("%s: wait %s exceeds cap", j, got)
+		}
+	}
+}
+
+func TestParseJitter(t *testing.T) {
+	if j, err := ParseJitter(""); err != nil || j != JitterNone {
+		t.Errorf("empty jitter: got %q, %v", j, err)
+	}
+	if j, err := ParseJitter("decorrelated"); err != nil || j != JitterDecorrelated {
+		t.Errorf("decorrelated: got %q, %v", j, err)
+	}
+	if _, err := ParseJitter("random"); err == nil {
+		t.Errorf("expected error for unknown jitter")
+	}
+}
+
+func TestBudget(t *testing.T) {
+	var unlimited *Budget
+	if !unlimited.Allows(time.Hour) {
+		t.Errorf("nil budget should be unlimited")
+	}
+
+	b := NewBudget(time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Consume(10 * time.Second)
+		}()
+	}
+	wg.Wait()
+
+	if b.Used() != 50*time.Second {
+		t.Fatalf("used = %s, want 50s", b.Used())
+	}
+	if !b.Allows(10 * time.Second) {
+		t.Errorf("expected 10s to fit into remaining budget")
+	}
+	if b.Allows(11 * time.Second) {
+		t.Errorf("expected 11s to exceed remaining budget")
+	}
+}
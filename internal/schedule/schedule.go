@@ -0,0 +1,78 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule yields the activation times of a recurring job.
+type Schedule interface {
+	// Next returns the first activation time after t.
+	Next(t time.Time) time.Time
+}
+
+// CatchUp selects what happens to activations that were missed because the
+// previous run was still going or the process was not running.
+type CatchUp string
+
+const (
+	// CatchUpSkip drops missed activations and waits for the next one.
+	CatchUpSkip CatchUp = "skip"
+	// CatchUpOnce runs once right away for any number of missed
+	// activations.
+	CatchUpOnce CatchUp = "once"
+)
+
+// ParseCatchUp validates a catch-up policy name.
+func ParseCatchUp(s string) (CatchUp, error) {
+	switch c := CatchUp(s); c {
+	case CatchUpSkip, CatchUpOnce:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown catch-up policy %q (want skip or once)", s)
+}
+
+// Parse parses a standard five-field cron expression, a descriptor such as
+// "@hourly" or "@every 10m", or a plain fixed interval such as "10m".
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, err := time.ParseDuration(spec); err == nil {
+		if d <= 0 {
+			return nil, fmt.Errorf("schedule interval must be positive, got %s", d)
+		}
+		return cron.Every(d), nil
+	}
+	s, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	return s, nil
+}
+
+// maxMissed bounds how many missed activations Next counts.
+const maxMissed = 1000
+
+// Next returns when a job that last ran at last should run next, given that
+// it is now. It also returns how many activations were missed since last, at
+// most maxMissed. Missed activations are dropped unless policy is
+// CatchUpOnce, in which case the job is due immediately.
+func Next(s Schedule, last, now time.Time, policy CatchUp) (time.Time, int) {
+	next := s.Next(last)
+	missed := 0
+	for next.Before(now) && missed < maxMissed {
+		missed++
+		next = s.Next(next)
+	}
+	if next.Before(now) {
+		// Stop counting after a long outage but still wait for the first
+		// activation to come.
+		next = s.Next(now)
+	}
+	if missed > 0 && policy == CatchUpOnce {
+		return now, missed
+	}
+	return next, missed
+}
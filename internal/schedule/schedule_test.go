@@ -0,0 +1,99 @@
+package schedule
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 3, 0, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/5 * * * *", time.Date(2024, 5, 1, 12, 5, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)},
+		{"@every 10m", base.Add(10 * time.Minute)},
+		{"90s", base.Add(90 * time.Second)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(base); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next() = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "every day", "* * *", "-5m", "0s"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) expected error", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	s, _ := Parse("*/5 * * * *")
+	last := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	now := last.Add(2 * time.Minute)
+	if next, missed := Next(s, last, now, CatchUpSkip); missed != 0 || !next.Equal(last.Add(5*time.Minute)) {
+		t.Fatalf("Next() = %s, %d, want 12:05 and no missed run", next, missed)
+	}
+
+	// The 12:05, 12:10 and 12:15 activations were missed.
+	now = last.Add(17 * time.Minute)
+	if next, missed := Next(s, last, now, CatchUpSkip); missed != 3 || !next.Equal(last.Add(20*time.Minute)) {
+		t.Fatalf("Next(skip) = %s, %d, want 12:20 and 3 missed runs", next, missed)
+	}
+	if next, missed := Next(s, last, now, CatchUpOnce); missed != 3 || !next.Equal(now) {
+		t.Fatalf("Next(once) = %s, %d, want now and 3 missed runs", next, missed)
+	}
+}
+
+func TestNextAfterLongOutage(t *testing.T) {
+	s, _ := Parse("*/5 * * * *")
+	last := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Ten days down is far more than maxMissed five-minute activations.
+	now := last.Add(10*24*time.Hour + 2*time.Minute)
+	next, missed := Next(s, last, now, CatchUpSkip)
+	if missed != maxMissed || !next.Equal(last.Add(10*24*time.Hour+5*time.Minute)) {
+		t.Fatalf("Next(skip) = %s, %d, want 12:05 ten days later and %d missed runs", next, missed, maxMissed)
+	}
+}
+
+func TestParseCatchUp(t *testing.T) {
+	if _, err := ParseCatchUp("once"); err != nil {
+		t.Fatalf("ParseCatchUp(once) error = %v", err)
+	}
+	if _, err := ParseCatchUp("all"); err == nil {
+		t.Fatal("ParseCatchUp(all) expected error")
+	}
+}
+
+func TestStatePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	s, err := OpenState(path)
+	if err != nil {
+		t.Fatalf("OpenState() error = %v", err)
+	}
+	if _, ok := s.LastRun("dims"); ok {
+		t.Fatal("unexpected last run in empty state")
+	}
+	ran := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.Record("dims", ran); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	loaded, err := OpenState(path)
+	if err != nil {
+		t.Fatalf("OpenState() error = %v", err)
+	}
+	if got, ok := loaded.LastRun("dims"); !ok || !got.Equal(ran) {
+		t.Fatalf("LastRun() = %s, %v, want %s", got, ok, ran)
+	}
+}
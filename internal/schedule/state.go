@@ -0,0 +1,62 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"sling-sync-wrapper/internal/fsutil"
+)
+
+// State remembers when each scheduled job last ran so that missed
+// activations can be detected across restarts. It is safe for concurrent
+// use.
+type State struct {
+	mu      sync.Mutex
+	path    string
+	lastRun map[string]time.Time
+}
+
+// OpenState loads the state at path, starting empty if the file does not
+// exist.
+func OpenState(path string) (*State, error) {
+	s := &State{path: path, lastRun: map[string]time.Time{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read schedule state: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.lastRun); err != nil {
+		return nil, fmt.Errorf("parse schedule state %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// LastRun returns when job last ran and whether that is known.
+func (s *State) LastRun(job string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.lastRun[job]
+	return t, ok
+}
+
+// Record stores that job ran at t.
+func (s *State) Record(job string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRun[job] = t
+	data, err := json.MarshalIndent(s.lastRun, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode schedule state: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("write schedule state: %w", err)
+	}
+	return nil
+}
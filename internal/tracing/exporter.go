@@ -0,0 +1,458 @@
+package tracing
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+// Options configures where telemetry goes. The OTLP settings follow the
+// OTEL_EXPORTER_OTLP_* variables of the OpenTelemetry specification.
+type Options struct {
+	ServiceName      string
+	MissionClusterID string
+	// Exporter is otlp, stdout, or file to write JSON lines to File.
+	Exporter string
+	File     string
+	// Protocol is grpc or http/protobuf.
+	Protocol string
+	// Endpoint is host:port or a URL, localhost with the default port of
+	// the protocol if empty. An https URL or a certificate turns on TLS;
+	// otherwise the connection is plaintext. For http/protobuf the signal
+	// path, such as /v1/traces, is appended to the URL path.
+	Endpoint string
+	// Headers are sent with every export, as comma separated key=value
+	// pairs with URL-encoded values.
+	Headers string
+	// Certificate is a PEM file of CAs to verify the collector with.
+	// ClientCertificate and ClientKey are PEM files for mTLS.
+	Certificate       string
+	ClientCertificate string
+	ClientKey         string
+	// Compression is gzip or none.
+	Compression string
+	// Timeout bounds each export; 0 keeps the exporter default.
+	Timeout time.Duration
+	// Sampler and SamplerArg follow OTEL_TRACES_SAMPLER and
+	// OTEL_TRACES_SAMPLER_ARG; an empty Sampler is parentbased_always_on.
+	Sampler    string
+	SamplerArg string
+	// Spool keeps spans the collector does not accept; OTLP only.
+	Spool *Spool
+}
+
+// Validate reports settings that cannot work without touching the network or
+// any files.
+func (o Options) Validate() error {
+	switch o.Exporter {
+	case "", "otlp", "stdout":
+	case "file":
+		if o.File == "" {
+			return fmt.Errorf("the file exporter needs a file")
+		}
+	default:
+		return fmt.Errorf("unknown telemetry exporter %q (want otlp, stdout or file)", o.Exporter)
+	}
+	switch o.Protocol {
+	case "", "grpc", "http/protobuf":
+	default:
+		return fmt.Errorf("unsupported OTLP protocol %q (want grpc or http/protobuf)", o.Protocol)
+	}
+	switch o.Compression {
+	case "", "none", "gzip":
+	default:
+		return fmt.Errorf("unsupported OTLP compression %q (want gzip or none)", o.Compression)
+	}
+	if (o.ClientCertificate == "") != (o.ClientKey == "") {
+		return fmt.Errorf("an OTLP client certificate needs a client key and vice versa")
+	}
+	if _, err := parseEndpoint(o.Endpoint); err != nil {
+		return err
+	}
+	if _, err := parseHeaders(o.Headers); err != nil {
+		return err
+	}
+	if _, err := newSampler(o.Sampler, o.SamplerArg); err != nil {
+		return err
+	}
+	return nil
+}
+
+// exporters are the trace, metric and log exporters of one Init.
+type exporters struct {
+	trace  sdktrace.SpanExporter
+	metric sdkmetric.Exporter
+	log    sdklog.Exporter
+	// close releases what the exporters write to once they are shut down.
+	close func() error
+}
+
+// newExporters creates the exporters selected by opts.
+func newExporters(ctx context.Context, opts Options) (*exporters, error) {
+	switch opts.Exporter {
+	case "stdout":
+		return newWriterExporters(nopCloser{os.Stdout})
+	case "file":
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("open telemetry file: %w", err)
+		}
+		return newWriterExporters(f)
+	}
+	return newOTLPExporters(ctx, opts)
+}
+
+// newWriterExporters returns exporters writing JSON lines to w.
+func newWriterExporters(w io.WriteCloser) (*exporters, error) {
+	lw := &lockedWriter{w: w}
+	traceExp, err := stdouttrace.New(stdouttrace.WithWriter(lw))
+	if err != nil {
+		return nil, errors.Join(err, w.Close())
+	}
+	metricExp, err := stdoutmetric.New(stdoutmetric.WithWriter(lw))
+	if err != nil {
+		return nil, errors.Join(err, w.Close())
+	}
+	logExp, err := stdoutlog.New(stdoutlog.WithWriter(lw))
+	if err != nil {
+		return nil, errors.Join(err, w.Close())
+	}
+	return &exporters{trace: traceExp, metric: metricExp, log: logExp, close: w.Close}, nil
+}
+
+func newOTLPExporters(ctx context.Context, opts Options) (*exporters, error) {
+	traceExp, err := newTraceExporter(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP trace exporter: %w", err)
+	}
+	metricExp, err := newMetricExporter(ctx, opts)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("create OTLP metric exporter: %w", err), traceExp.Shutdown(ctx))
+	}
+	logExp, err := newLogExporter(ctx, opts)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("create OTLP log exporter: %w", err), traceExp.Shutdown(ctx), metricExp.Shutdown(ctx))
+	}
+	return &exporters{trace: traceExp, metric: metricExp, log: logExp, close: func() error { return nil }}, nil
+}
+
+// otlpSettings are Options resolved for the OTLP exporters.
+type otlpSettings struct {
+	http    bool
+	host    string
+	path    string
+	tls     *tls.Config
+	headers map[string]string
+	gzip    bool
+	timeout time.Duration
+}
+
+func resolveOTLP(opts Options) (otlpSettings, error) {
+	ep, err := parseEndpoint(opts.Endpoint)
+	if err != nil {
+		return otlpSettings{}, err
+	}
+	headers, err := parseHeaders(opts.Headers)
+	if err != nil {
+		return otlpSettings{}, err
+	}
+	s := otlpSettings{
+		http:    opts.Protocol == "http/protobuf",
+		host:    ep.host,
+		path:    ep.path,
+		headers: headers,
+		gzip:    opts.Compression == "gzip",
+		timeout: opts.Timeout,
+	}
+	if s.host == "" {
+		s.host = "localhost:4317"
+		if s.http {
+			s.host = "localhost:4318"
+		}
+	}
+	if ep.tls || opts.Certificate != "" || opts.ClientCertificate != "" {
+		if s.tls, err = tlsConfig(opts); err != nil {
+			return otlpSettings{}, err
+		}
+	}
+	return s, nil
+}
+
+// newTraceClient returns the OTLP client that delivers spans. retry is off
+// when the caller keeps undelivered spans itself.
+func newTraceClient(opts Options, retry bool) (otlptrace.Client, error) {
+	s, err := resolveOTLP(opts)
+	if err != nil {
+		return nil, err
+	}
+	if s.http {
+		o := []otlptracehttp.Option{otlptracehttp.WithEndpoint(s.host), otlptracehttp.WithURLPath(s.path + "/v1/traces"),
+			otlptracehttp.WithHeaders(s.headers)}
+		if s.tls != nil {
+			o = append(o, otlptracehttp.WithTLSClientConfig(s.tls))
+		} else {
+			o = append(o, otlptracehttp.WithInsecure())
+		}
+		if s.gzip {
+			o = append(o, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+		if s.timeout > 0 {
+			o = append(o, otlptracehttp.WithTimeout(s.timeout))
+		}
+		if !retry {
+			o = append(o, otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}))
+		}
+		return otlptracehttp.NewClient(o...), nil
+	}
+	o := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(s.host), otlptracegrpc.WithHeaders(s.headers)}
+	if s.tls != nil {
+		o = append(o, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(s.tls)))
+	} else {
+		o = append(o, otlptracegrpc.WithInsecure())
+	}
+	if s.gzip {
+		o = append(o, otlptracegrpc.WithCompressor("gzip"))
+	}
+	if s.timeout > 0 {
+		o = append(o, otlptracegrpc.WithTimeout(s.timeout))
+	}
+	if !retry {
+		o = append(o, otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{Enabled: false}))
+	}
+	return otlptracegrpc.NewClient(o...), nil
+}
+
+// newTraceExporter returns an OTLP trace exporter that spools undelivered
+// spans if opts has a spool.
+func newTraceExporter(ctx context.Context, opts Options) (*otlptrace.Exporter, error) {
+	// With a spool, the spool takes over retrying, so that an unreachable
+	// collector does not hold up shutdown.
+	client, err := newTraceClient(opts, opts.Spool == nil)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Spool != nil {
+		client = &spoolingClient{Client: client, spool: opts.Spool}
+	}
+	return otlptrace.New(ctx, client)
+}
+
+func newMetricExporter(ctx context.Context, opts Options) (sdkmetric.Exporter, error) {
+	s, err := resolveOTLP(opts)
+	if err != nil {
+		return nil, err
+	}
+	if s.http {
+		o := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(s.host), otlpmetrichttp.WithURLPath(s.path + "/v1/metrics"),
+			otlpmetrichttp.WithHeaders(s.headers)}
+		if s.tls != nil {
+			o = append(o, otlpmetrichttp.WithTLSClientConfig(s.tls))
+		} else {
+			o = append(o, otlpmetrichttp.WithInsecure())
+		}
+		if s.gzip {
+			o = append(o, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+		}
+		if s.timeout > 0 {
+			o = append(o, otlpmetrichttp.WithTimeout(s.timeout))
+		}
+		return otlpmetrichttp.New(ctx, o...)
+	}
+	o := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(s.host), otlpmetricgrpc.WithHeaders(s.headers)}
+	if s.tls != nil {
+		o = append(o, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(s.tls)))
+	} else {
+		o = append(o, otlpmetricgrpc.WithInsecure())
+	}
+	if s.gzip {
+		o = append(o, otlpmetricgrpc.WithCompressor("gzip"))
+	}
+	if s.timeout > 0 {
+		o = append(o, otlpmetricgrpc.WithTimeout(s.timeout))
+	}
+	return otlpmetricgrpc.New(ctx, o...)
+}
+
+func newLogExporter(ctx context.Context, opts Options) (sdklog.Exporter, error) {
+	s, err := resolveOTLP(opts)
+	if err != nil {
+		return nil, err
+	}
+	if s.http {
+		o := []otlploghttp.Option{otlploghttp.WithEndpoint(s.host), otlploghttp.WithURLPath(s.path + "/v1/logs"),
+			otlploghttp.WithHeaders(s.headers)}
+		if s.tls != nil {
+			o = append(o, otlploghttp.WithTLSClientConfig(s.tls))
+		} else {
+			o = append(o, otlploghttp.WithInsecure())
+		}
+		if s.gzip {
+			o = append(o, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+		}
+		if s.timeout > 0 {
+			o = append(o, otlploghttp.WithTimeout(s.timeout))
+		}
+		return otlploghttp.New(ctx, o...)
+	}
+	o := []otlploggrpc.Option{otlploggrpc.WithEndpoint(s.host), otlploggrpc.WithHeaders(s.headers)}
+	if s.tls != nil {
+		o = append(o, otlploggrpc.WithTLSCredentials(credentials.NewTLS(s.tls)))
+	} else {
+		o = append(o, otlploggrpc.WithInsecure())
+	}
+	if s.gzip {
+		o = append(o, otlploggrpc.WithCompressor("gzip"))
+	}
+	if s.timeout > 0 {
+		o = append(o, otlploggrpc.WithTimeout(s.timeout))
+	}
+	return otlploggrpc.New(ctx, o...)
+}
+
+type endpoint struct {
+	host string
+	path string
+	tls  bool
+}
+
+// parseEndpoint accepts host:port as well as http and https URLs.
+func parseEndpoint(s string) (endpoint, error) {
+	if !strings.Contains(s, "://") {
+		return endpoint{host: s}, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return endpoint{}, fmt.Errorf("invalid OTLP endpoint %q: %w", s, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return endpoint{}, fmt.Errorf("invalid OTLP endpoint %q: scheme must be http or https", s)
+	}
+	if u.Host == "" {
+		return endpoint{}, fmt.Errorf("invalid OTLP endpoint %q: no host", s)
+	}
+	return endpoint{host: u.Host, path: strings.TrimSuffix(u.Path, "/"), tls: u.Scheme == "https"}, nil
+}
+
+// parseHeaders parses comma separated key=value pairs with URL-encoded
+// values, the format of OTEL_EXPORTER_OTLP_HEADERS.
+func parseHeaders(s string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid OTLP header %q: want key=value", pair)
+		}
+		value, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP header %q: %w", k, err)
+		}
+		headers[k] = value
+	}
+	return headers, nil
+}
+
+func tlsConfig(opts Options) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.Certificate != "" {
+		pem, err := os.ReadFile(opts.Certificate)
+		if err != nil {
+			return nil, fmt.Errorf("read OTLP certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.Certificate)
+		}
+		cfg.RootCAs = pool
+	}
+	if opts.ClientCertificate != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertificate, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load OTLP client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// newSampler returns the sampler named like the values of
+// OTEL_TRACES_SAMPLER.
+func newSampler(name, arg string) (sdktrace.Sampler, error) {
+	ratio := func() (float64, error) {
+		if arg == "" {
+			return 1, nil
+		}
+		r, err := strconv.ParseFloat(arg, 64)
+		if err != nil || r < 0 || r > 1 {
+			return 0, fmt.Errorf("invalid sampler argument %q: want a ratio between 0 and 1", arg)
+		}
+		return r, nil
+	}
+	switch name {
+	case "", "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "traceidratio":
+		r, err := ratio()
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.TraceIDRatioBased(r), nil
+	case "parentbased_traceidratio":
+		r, err := ratio()
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(r)), nil
+	}
+	return nil, fmt.Errorf("unknown traces sampler %q", name)
+}
+
+// lockedWriter serializes the writes of the exporters sharing a writer, so
+// that their JSON lines do not interleave.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,282 @@
+package tracing
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"defaults", Options{}, false},
+		{"http", Options{Protocol: "http/protobuf", Endpoint: "https://proxy:4318/otlp", Compression: "gzip"}, false},
+		{"file", Options{Exporter: "file", File: "telemetry.jsonl"}, false},
+		{"file without path", Options{Exporter: "file"}, true},
+		{"unknown exporter", Options{Exporter: "jaeger"}, true},
+		{"unknown protocol", Options{Protocol: "http/json"}, true},
+		{"unknown compression", Options{Compression: "zstd"}, true},
+		{"client cert without key", Options{ClientCertificate: "client.pem"}, true},
+		{"bad endpoint scheme", Options{Endpoint: "ftp://collector"}, true},
+		{"bad header", Options{Headers: "novalue"}, true},
+		{"unknown sampler", Options{Sampler: "sometimes"}, true},
+		{"bad sampler ratio", Options{Sampler: "traceidratio", SamplerArg: "2"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got, err := parseHeaders("authorization=Bearer%20abc, x-tenant = mission-01,")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(got) != 2 || got["authorization"] != "Bearer abc" || got["x-tenant"] != "mission-01" {
+		t.Errorf("headers = %v", got)
+	}
+}
+
+func TestNewSampler(t *testing.T) {
+	tests := map[string]string{
+		"":                         "ParentBased{root:AlwaysOnSampler",
+		"always_off":               "AlwaysOffSampler",
+		"traceidratio":             "TraceIDRatioBased{0.5}",
+		"parentbased_traceidratio": "ParentBased{root:TraceIDRatioBased{0.5}",
+	}
+	for name, want := range tests {
+		s, err := newSampler(name, "0.5")
+		if err != nil {
+			t.Fatalf("%q: %v", name, err)
+		}
+		if !strings.HasPrefix(s.Description(), want) {
+			t.Errorf("%q sampler = %s, want %s", name, s.Description(), want)
+		}
+	}
+}
+
+// writeCerts writes a CA and a server and a client certificate signed by it
+// to dir and returns the TLS config of a server that requires the client
+// certificate.
+func writeCerts(t *testing.T, dir string) *tls.Config {
+	t.Helper()
+	newKey := func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	writePEM := func(name, typ string, der []byte) {
+		if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	caKey := newKey()
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+	writePEM("ca.pem", "CERTIFICATE", caDER)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) tls.Certificate {
+		key := newKey()
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "localhost"},
+			DNSNames:     []string{"localhost"},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	server := issue(2, x509.ExtKeyUsageServerAuth)
+	client := issue(3, x509.ExtKeyUsageClientAuth)
+	writePEM("client.pem", "CERTIFICATE", client.Certificate[0])
+	keyDER, err := x509.MarshalECPrivateKey(client.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM("client-key.pem", "EC PRIVATE KEY", keyDER)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return &tls.Config{
+		Certificates: []tls.Certificate{server},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+}
+
+// headerServer is an OTLP trace receiver that keeps the metadata of the
+// requests it receives.
+type headerServer struct {
+	otlpServer
+	mu sync.Mutex
+	md []metadata.MD
+}
+
+func (s *headerServer) Export(ctx context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mu.Lock()
+	s.md = append(s.md, md)
+	s.mu.Unlock()
+	return s.otlpServer.Export(ctx, req)
+}
+
+func TestTraceExporterGRPCMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverTLS := writeCerts(t, dir)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverTLS)))
+	otlp := &headerServer{}
+	collectortrace.RegisterTraceServiceServer(server, otlp)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	exportSpan(t, Options{
+		Endpoint:          lis.Addr().String(),
+		Headers:           "authorization=Bearer%20secret",
+		Certificate:       filepath.Join(dir, "ca.pem"),
+		ClientCertificate: filepath.Join(dir, "client.pem"),
+		ClientKey:         filepath.Join(dir, "client-key.pem"),
+		Compression:       "gzip",
+	}, "secure")
+
+	otlp.mu.Lock()
+	defer otlp.mu.Unlock()
+	if got := requestNames(otlp.requests); len(got) != 1 || got[0] != "secure" {
+		t.Fatalf("collector received %v, want [secure]", got)
+	}
+	if got := otlp.md[0].Get("authorization"); len(got) != 1 || got[0] != "Bearer secret" {
+		t.Errorf("authorization = %v", got)
+	}
+}
+
+func TestTraceExporterHTTP(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		paths    []string
+		auth     []string
+		encoding []string
+		received []*collectortrace.ExportTraceServiceRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+		data, _ := io.ReadAll(body)
+		var req collectortrace.ExportTraceServiceRequest
+		if err := proto.Unmarshal(data, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		auth = append(auth, r.Header.Get("Authorization"))
+		encoding = append(encoding, r.Header.Get("Content-Encoding"))
+		received = append(received, &req)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		resp, _ := proto.Marshal(&collectortrace.ExportTraceServiceResponse{})
+		w.Write(resp)
+	}))
+	defer srv.Close()
+
+	exportSpan(t, Options{
+		Protocol:    "http/protobuf",
+		Endpoint:    srv.URL + "/otlp/",
+		Headers:     "authorization=Bearer%20secret",
+		Compression: "gzip",
+	}, "proxied")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := requestNames(received); len(got) != 1 || got[0] != "proxied" {
+		t.Fatalf("collector received %v, want [proxied]", got)
+	}
+	if paths[0] != "/otlp/v1/traces" || auth[0] != "Bearer secret" || encoding[0] != "gzip" {
+		t.Errorf("request to %s with authorization %q and encoding %q", paths[0], auth[0], encoding[0])
+	}
+}
+
+func TestFileExporters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	exp, err := newExporters(context.Background(), Options{Exporter: "file", File: path})
+	if err != nil {
+		t.Fatalf("exporters: %v", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp.trace))
+	_, span := tp.Tracer("test").Start(context.Background(), "local")
+	span.End()
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if err := exp.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Name":"local"`) {
+		t.Errorf("telemetry file lacks the span:\n%s", data)
+	}
+}
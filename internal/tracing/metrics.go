@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// meterName is the instrumentation scope of the wrapper's instruments.
+const meterName = "sling-sync-wrapper"
+
+// Instruments are the OpenTelemetry metric instruments of the wrapper. Every
+// measurement carries the mission cluster and pipeline. All methods do
+// nothing on a nil *Instruments.
+type Instruments struct {
+	missionClusterID attribute.KeyValue
+	runs             metric.Int64Counter
+	retries          metric.Int64Counter
+	rows             metric.Int64Counter
+	slingDuration    metric.Float64Histogram
+	parseFailures    metric.Int64Counter
+}
+
+// NewInstruments creates the wrapper's instruments from mp.
+func NewInstruments(mp metric.MeterProvider, missionClusterID string) (*Instruments, error) {
+	m := mp.Meter(meterName)
+	i := &Instruments{missionClusterID: attribute.String("mission_cluster_id", missionClusterID)}
+	var err error
+	if i.runs, err = m.Int64Counter("sling.sync.runs",
+		metric.WithDescription("Pipeline runs by final status."), metric.WithUnit("{run}")); err != nil {
+		return nil, err
+	}
+	if i.retries, err = m.Int64Counter("sling.sync.retries",
+		metric.WithDescription("Retried Sling invocations by error class."), metric.WithUnit("{retry}")); err != nil {
+		return nil, err
+	}
+	if i.rows, err = m.Int64Counter("sling.sync.rows_synced",
+		metric.WithDescription("Rows synced."), metric.WithUnit("{row}")); err != nil {
+		return nil, err
+	}
+	if i.slingDuration, err = m.Float64Histogram("sling.sync.sling.duration",
+		metric.WithDescription("Duration of single Sling invocations."), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if i.parseFailures, err = m.Int64Counter("sling.sync.log_parse_failures",
+		metric.WithDescription("Sling log lines that could not be parsed."), metric.WithUnit("{line}")); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (i *Instruments) attrs(pipeline string, extra ...attribute.KeyValue) metric.MeasurementOption {
+	return metric.WithAttributes(append([]attribute.KeyValue{i.missionClusterID, attribute.String("pipeline", pipeline)}, extra...)...)
+}
+
+// RecordRun counts a finished pipeline run and the rows it synced.
+func (i *Instruments) RecordRun(ctx context.Context, pipeline, status string, rows int) {
+	if i == nil {
+		return
+	}
+	i.runs.Add(ctx, 1, i.attrs(pipeline, attribute.String("status", status)))
+	if rows > 0 {
+		i.rows.Add(ctx, int64(rows), i.attrs(pipeline))
+	}
+}
+
+// RecordRetry counts a retry after a failure of the given error class.
+func (i *Instruments) RecordRetry(ctx context.Context, pipeline, errorClass string) {
+	if i == nil {
+		return
+	}
+	i.retries.Add(ctx, 1, i.attrs(pipeline, attribute.String("error.class", errorClass)))
+}
+
+// RecordSling records how long a Sling invocation took and whether it
+// succeeded.
+func (i *Instruments) RecordSling(ctx context.Context, pipeline string, took time.Duration, err error) {
+	if i == nil {
+		return
+	}
+	status := "success"
+	if err != nil {
+		status = "failed"
+	}
+	i.slingDuration.Record(ctx, took.Seconds(), i.attrs(pipeline, attribute.String("status", status)))
+}
+
+// RecordParseFailure counts a Sling log line that could not be parsed.
+func (i *Instruments) RecordParseFailure(ctx context.Context, pipeline string) {
+	if i == nil {
+		return
+	}
+	i.parseFailures.Add(ctx, 1, i.attrs(pipeline))
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestInstruments(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	inst, err := NewInstruments(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)), "mc")
+	if err != nil {
+		t.Fatalf("NewInstruments() error = %v", err)
+	}
+	ctx := context.Background()
+	inst.RecordSling(ctx, "dims", 2*time.Second, errors.New("boom"))
+	inst.RecordRetry(ctx, "dims", "transient")
+	inst.RecordSling(ctx, "dims", 3*time.Second, nil)
+	inst.RecordRun(ctx, "dims", "success", 42)
+	inst.RecordParseFailure(ctx, "dims")
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = m.Data
+		}
+	}
+	for _, name := range []string{"sling.sync.runs", "sling.sync.retries", "sling.sync.rows_synced", "sling.sync.sling.duration", "sling.sync.log_parse_failures"} {
+		if got[name] == nil {
+			t.Errorf("instrument %s not recorded", name)
+		}
+	}
+
+	rows := got["sling.sync.rows_synced"].(metricdata.Sum[int64]).DataPoints[0]
+	if rows.Value != 42 {
+		t.Errorf("rows synced = %d, want 42", rows.Value)
+	}
+	for _, kv := range []attribute.KeyValue{attribute.String("mission_cluster_id", "mc"), attribute.String("pipeline", "dims")} {
+		if v, ok := rows.Attributes.Value(kv.Key); !ok || v != kv.Value {
+			t.Errorf("rows synced attribute %s = %v, want %v", kv.Key, v, kv.Value)
+		}
+	}
+	if n := len(got["sling.sync.sling.duration"].(metricdata.Histogram[float64]).DataPoints); n != 2 {
+		t.Errorf("Sling duration has %d series, want one per status", n)
+	}
+
+	var nilInst *Instruments
+	nilInst.RecordRun(ctx, "dims", "success", 1)
+}
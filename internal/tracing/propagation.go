@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// propagator carries the W3C trace context and baggage.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Environ returns the trace context and baggage of ctx as environment
+// variables (TRACEPARENT, TRACESTATE and BAGGAGE), so that a child process
+// can continue the trace. Variables without a value are left out.
+func Environ(ctx context.Context) []string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	env := make([]string, 0, len(carrier))
+	for key, value := range carrier {
+		env = append(env, strings.ToUpper(key)+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// ContextWithParent returns ctx carrying the remote span described by the W3C
+// traceparent and tracestate values, so that spans started from it continue
+// the caller's trace. An empty traceparent returns ctx unchanged; an invalid
+// tracestate is dropped.
+func ContextWithParent(ctx context.Context, traceparent, tracestate string) (context.Context, error) {
+	if traceparent == "" {
+		return ctx, nil
+	}
+	carrier := propagation.MapCarrier{"traceparent": traceparent, "tracestate": tracestate}
+	sc := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+	if !sc.IsValid() {
+		return ctx, fmt.Errorf("invalid traceparent %q", traceparent)
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc), nil
+}
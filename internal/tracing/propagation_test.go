@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestEnviron(t *testing.T) {
+	if env := Environ(context.Background()); len(env) != 0 {
+		t.Errorf("Environ without span = %v, want none", env)
+	}
+
+	state, err := trace.ParseTraceState("vendor=value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+		TraceState: state,
+	})
+	got := Environ(trace.ContextWithSpanContext(context.Background(), sc))
+	want := []string{
+		"TRACEPARENT=00-01000000000000000000000000000000-0200000000000000-01",
+		"TRACESTATE=vendor=value",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Environ = %v, want %v", got, want)
+	}
+}
+
+func TestContextWithParent(t *testing.T) {
+	ctx, err := ContextWithParent(context.Background(), "", "")
+	if err != nil || trace.SpanContextFromContext(ctx).IsValid() {
+		t.Errorf("empty traceparent = %v, %v; want no parent", trace.SpanContextFromContext(ctx), err)
+	}
+
+	ctx, err = ContextWithParent(context.Background(),
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "vendor=value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if sc.TraceID().String() != "0af7651916cd43dd8448eb211c80319c" || sc.SpanID().String() != "b7ad6b7169203331" ||
+		!sc.IsRemote() || !sc.IsSampled() || sc.TraceState().Get("vendor") != "value" {
+		t.Errorf("parent = %+v", sc)
+	}
+
+	if _, err := ContextWithParent(context.Background(), "00-not-a-trace-01", ""); err == nil {
+		t.Error("expected error for invalid traceparent")
+	}
+}
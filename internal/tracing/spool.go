@@ -0,0 +1,191 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"sling-sync-wrapper/internal/fsutil"
+)
+
+// spoolExt is the extension of spooled batches.
+const spoolExt = ".pb"
+
+// Spool keeps span batches that could not be delivered to the collector in a
+// directory, one OTLP ExportTraceServiceRequest protobuf file per batch.
+// Files are named after the time they were spooled so that they replay
+// oldest first. When the spool grows past its size cap the oldest batches
+// are dropped.
+type Spool struct {
+	dir      string
+	maxBytes int64
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewSpool returns a spool in dir holding at most maxBytes of batches. The
+// directory is created when the first batch is spooled.
+func NewSpool(dir string, maxBytes int64) *Spool {
+	return &Spool{dir: dir, maxBytes: maxBytes}
+}
+
+// Add writes a batch to the spool and evicts the oldest batches if the spool
+// exceeds its size cap.
+func (s *Spool) Add(spans []*tracepb.ResourceSpans) error {
+	data, err := proto.Marshal(&collectortrace.ExportTraceServiceRequest{ResourceSpans: spans})
+	if err != nil {
+		return fmt.Errorf("encode spans: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.seq, spoolExt)
+	if err := fsutil.WriteFileAtomic(filepath.Join(s.dir, name), data); err != nil {
+		return fmt.Errorf("spool spans: %w", err)
+	}
+	return s.evict()
+}
+
+// evict removes the oldest batches until the spool fits its size cap.
+func (s *Spool) evict() error {
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	dropped := 0
+	for _, f := range files {
+		if total <= s.maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("evict spooled spans: %w", err)
+		}
+		total -= f.size
+		dropped++
+	}
+	if dropped > 0 {
+		otel.Handle(fmt.Errorf("span spool %s is full, dropped the %d oldest batches", s.dir, dropped))
+	}
+	return nil
+}
+
+// Flush uploads the spooled batches oldest first and removes each one that
+// was delivered. It stops at the first failed upload and returns the number
+// of batches delivered.
+func (s *Spool) Flush(ctx context.Context, upload func(context.Context, []*tracepb.ResourceSpans) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := s.files()
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, f := range files {
+		data, err := os.ReadFile(f.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Flushed by another process sharing the spool.
+			continue
+		}
+		if err != nil {
+			return sent, fmt.Errorf("read spooled spans: %w", err)
+		}
+		var req collectortrace.ExportTraceServiceRequest
+		if err := proto.Unmarshal(data, &req); err != nil {
+			// A corrupt batch would block the spool forever.
+			otel.Handle(fmt.Errorf("dropping unreadable spooled spans %s: %w", f.path, err))
+			os.Remove(f.path)
+			continue
+		}
+		if err := upload(ctx, req.ResourceSpans); err != nil {
+			return sent, fmt.Errorf("upload spooled spans: %w", err)
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return sent, fmt.Errorf("remove spooled spans: %w", err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Pending returns the number of spooled batches and their total size.
+func (s *Spool) Pending() (int, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := s.files()
+	if err != nil {
+		return 0, 0, err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	return len(files), total, nil
+}
+
+type spoolFile struct {
+	path string
+	size int64
+}
+
+// files lists the spooled batches oldest first.
+func (s *Spool) files() ([]spoolFile, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list span spool: %w", err)
+	}
+	var files []spoolFile
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), spoolExt) || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, spoolFile{path: filepath.Join(s.dir, e.Name()), size: info.Size()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
+
+// spoolingClient delivers spans through an OTLP client, replaying the spool
+// before each batch. Batches that cannot be delivered go to the spool
+// instead of being lost.
+type spoolingClient struct {
+	otlptrace.Client
+	spool *Spool
+}
+
+func (c *spoolingClient) UploadTraces(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+	if _, err := c.spool.Flush(ctx, c.Client.UploadTraces); err != nil {
+		// The backlog goes first, so new spans queue up behind it.
+		return c.spool.Add(spans)
+	}
+	if err := c.Client.UploadTraces(ctx, spans); err != nil {
+		if spoolErr := c.spool.Add(spans); spoolErr != nil {
+			return errors.Join(err, spoolErr)
+		}
+	}
+	return nil
+}
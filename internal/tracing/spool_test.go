@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+)
+
+func testBatch(name string) []*tracepb.ResourceSpans {
+	return []*tracepb.ResourceSpans{{ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{Name: name}}}}}}
+}
+
+// spanNames returns the names of the spans in batches, in order.
+func spanNames(batches ...[]*tracepb.ResourceSpans) []string {
+	var names []string
+	for _, batch := range batches {
+		for _, rs := range batch {
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					names = append(names, s.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+func requestNames(reqs []*collectortrace.ExportTraceServiceRequest) []string {
+	var batches [][]*tracepb.ResourceSpans
+	for _, req := range reqs {
+		batches = append(batches, req.ResourceSpans)
+	}
+	return spanNames(batches...)
+}
+
+func TestSpoolEvictsOldest(t *testing.T) {
+	spool := NewSpool(t.TempDir(), 1<<20)
+	for _, name := range []string{"a", "b", "c"} {
+		if err := spool.Add(testBatch(name)); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+	n, size, err := spool.Pending()
+	if err != nil || n != 3 {
+		t.Fatalf("pending = %d, %v; want 3", n, err)
+	}
+
+	// Cap the spool at two batches.
+	spool.maxBytes = size * 2 / 3
+	if err := spool.Add(testBatch("d")); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	var got []string
+	sent, err := spool.Flush(context.Background(), func(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+		got = append(got, spanNames(spans)...)
+		return nil
+	})
+	if err != nil || sent != 2 {
+		t.Fatalf("flush = %d, %v; want 2", sent, err)
+	}
+	if len(got) != 2 || got[0] != "c" || got[1] != "d" {
+		t.Errorf("flushed %v, want [c d]", got)
+	}
+	if n, _, _ := spool.Pending(); n != 0 {
+		t.Errorf("%d batches left after flush", n)
+	}
+}
+
+func TestSpoolFlushStopsOnFailure(t *testing.T) {
+	spool := NewSpool(t.TempDir(), 1<<20)
+	for _, name := range []string{"a", "b"} {
+		if err := spool.Add(testBatch(name)); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+	calls := 0
+	sent, err := spool.Flush(context.Background(), func(ctx context.Context, spans []*tracepb.ResourceSpans) error {
+		calls++
+		return errors.New("unavailable")
+	})
+	if err == nil || sent != 0 || calls != 1 {
+		t.Fatalf("flush = %d, %v after %d uploads; want an error after 1", sent, err, calls)
+	}
+	if n, _, _ := spool.Pending(); n != 2 {
+		t.Errorf("%d batches left, want 2", n)
+	}
+}
+
+// exportSpan exports a span named name through a trace exporter for opts
+// and shuts the exporter down.
+func exportSpan(t *testing.T, opts Options, name string) {
+	t.Helper()
+	ctx := context.Background()
+	exp, err := newTraceExporter(ctx, opts)
+	if err != nil {
+		t.Fatalf("exporter: %v", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	_, span := tp.Tracer("test").Start(ctx, name)
+	span.End()
+	if err := tp.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+}
+
+func TestTraceExporterReplaysSpool(t *testing.T) {
+	spool := NewSpool(t.TempDir(), 1<<20)
+
+	// Nothing listens on the endpoint, so the span ends up in the spool.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	endpoint := lis.Addr().String()
+	lis.Close()
+	exportSpan(t, Options{Endpoint: endpoint, Spool: spool}, "offline")
+	if n, _, _ := spool.Pending(); n != 1 {
+		t.Fatalf("%d batches spooled, want 1", n)
+	}
+
+	lis, err = net.Listen("tcp", endpoint)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer()
+	otlp := &otlpServer{}
+	collectortrace.RegisterTraceServiceServer(server, otlp)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	exportSpan(t, Options{Endpoint: endpoint, Spool: spool}, "online")
+
+	otlp.mu.Lock()
+	got := requestNames(otlp.requests)
+	otlp.mu.Unlock()
+	if len(got) != 2 || got[0] != "offline" || got[1] != "online" {
+		t.Errorf("collector received %v, want [offline online]", got)
+	}
+	if n, _, _ := spool.Pending(); n != 0 {
+		t.Errorf("%d batches left in the spool", n)
+	}
+}
@@ -2,37 +2,82 @@ package tracing
 
 import (
 	"context"
-	"os"
+	"errors"
+	"fmt"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"go.opentelemetry.io/otel/trace"
-
-	"sling-sync-wrapper/internal/logging"
 )
 
-// Init sets up an OTEL tracer and returns it along with a shutdown function.
-func Init(ctx context.Context, serviceName, missionClusterID, endpoint string) (trace.Tracer, func(context.Context) error) {
-	exp, err := otlptracegrpc.New(ctx,
-		otlptracegrpc.WithInsecure(),
-		otlptracegrpc.WithEndpoint(endpoint))
+// Init sets up OTEL trace, meter and logger providers exporting as opts
+// configures and installs them globally. It returns a tracer along with a
+// shutdown function that flushes all three providers. If an exporter cannot
+// be created, nothing is installed and the error is returned.
+//
+// With a spool, spans that cannot be delivered are kept in it and the
+// backlog is replayed ahead of new spans once the collector is reachable.
+func Init(ctx context.Context, opts Options) (trace.Tracer, func(context.Context) error, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+	sampler, err := newSampler(opts.Sampler, opts.SamplerArg)
+	if err != nil {
+		return nil, nil, err
+	}
+	exp, err := newExporters(ctx, opts)
 	if err != nil {
-		logging.FromContext(ctx).Error("failed to create OTLP trace exporter", "err", err)
-		os.Exit(1)
+		return nil, nil, err
 	}
 
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String(opts.ServiceName),
+		attribute.String("mission_cluster_id", opts.MissionClusterID),
+	)
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exp),
-		sdktrace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String(serviceName),
-			attribute.String("mission_cluster_id", missionClusterID),
-		)),
+		sdktrace.WithBatcher(exp.trace),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithResource(res),
+	)
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp.metric)),
+		sdkmetric.WithResource(res),
+	)
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exp.log)),
+		sdklog.WithResource(res),
 	)
 	otel.SetTracerProvider(tp)
-	return tp.Tracer(serviceName), tp.Shutdown
+	otel.SetMeterProvider(mp)
+	// Loggers from logging.New pick up the provider in place.
+	global.SetLoggerProvider(lp)
+	shutdown := func(ctx context.Context) error {
+		// Shutting down the meter provider exports the final readings. The
+		// logger provider goes last so that it still ships errors logged
+		// while the others shut down.
+		return errors.Join(tp.Shutdown(ctx), mp.Shutdown(ctx), lp.Shutdown(ctx), exp.close())
+	}
+	return tp.Tracer(opts.ServiceName), shutdown, nil
+}
+
+// FlushSpool delivers the batches in opts.Spool to the OTLP collector of
+// opts. It returns the number of batches delivered and gives up at the first
+// batch the collector does not accept.
+func FlushSpool(ctx context.Context, opts Options) (int, error) {
+	client, err := newTraceClient(opts, false)
+	if err != nil {
+		return 0, err
+	}
+	if err := client.Start(ctx); err != nil {
+		return 0, fmt.Errorf("connect to collector: %w", err)
+	}
+	defer client.Stop(context.WithoutCancel(ctx))
+	return opts.Spool.Flush(ctx, client.UploadTraces)
 }
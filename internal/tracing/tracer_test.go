@@ -1,13 +1,19 @@
 package tracing
 
 import (
+	"bytes"
 	"context"
 	"net"
 	"sync"
 	"testing"
 
+	"go.opentelemetry.io/otel"
+	collectorlogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
 	"google.golang.org/grpc"
+
+	"sling-sync-wrapper/internal/logging"
 )
 
 type otlpServer struct {
@@ -23,6 +29,32 @@ func (s *otlpServer) Export(ctx context.Context, req *collectortrace.ExportTrace
 	return &collectortrace.ExportTraceServiceResponse{}, nil
 }
 
+type otlpMetricsServer struct {
+	collectormetrics.UnimplementedMetricsServiceServer
+	mu       sync.Mutex
+	requests []*collectormetrics.ExportMetricsServiceRequest
+}
+
+func (s *otlpMetricsServer) Export(ctx context.Context, req *collectormetrics.ExportMetricsServiceRequest) (*collectormetrics.ExportMetricsServiceResponse, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+	return &collectormetrics.ExportMetricsServiceResponse{}, nil
+}
+
+type otlpLogsServer struct {
+	collectorlogs.UnimplementedLogsServiceServer
+	mu       sync.Mutex
+	requests []*collectorlogs.ExportLogsServiceRequest
+}
+
+func (s *otlpLogsServer) Export(ctx context.Context, req *collectorlogs.ExportLogsServiceRequest) (*collectorlogs.ExportLogsServiceResponse, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+	return &collectorlogs.ExportLogsServiceResponse{}, nil
+}
+
 func TestInit(t *testing.T) {
 	lis, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
@@ -31,12 +63,26 @@ func TestInit(t *testing.T) {
 	server := grpc.NewServer()
 	otlp := &otlpServer{}
 	collectortrace.RegisterTraceServiceServer(server, otlp)
+	metrics := &otlpMetricsServer{}
+	collectormetrics.RegisterMetricsServiceServer(server, metrics)
+	logs := &otlpLogsServer{}
+	collectorlogs.RegisterLogsServiceServer(server, logs)
 	go server.Serve(lis)
 	defer server.Stop()
 
-	tracer, shutdown := Init(context.Background(), "svc", "mc", lis.Addr().String())
+	logger := logging.New()
+	tracer, shutdown, err := Init(context.Background(), Options{ServiceName: "svc", MissionClusterID: "mc", Endpoint: lis.Addr().String()})
+	if err != nil {
+		t.Fatalf("init: %v", err)
+	}
 	ctx, span := tracer.Start(context.Background(), "test")
+	logger.InfoContext(ctx, "pipeline completed")
 	span.End()
+	inst, err := NewInstruments(otel.GetMeterProvider(), "mc")
+	if err != nil {
+		t.Fatalf("instruments: %v", err)
+	}
+	inst.RecordRun(ctx, "dims", "success", 10)
 	if err := shutdown(ctx); err != nil {
 		t.Fatalf("shutdown: %v", err)
 	}
@@ -47,4 +93,29 @@ func TestInit(t *testing.T) {
 	if received == 0 {
 		t.Errorf("no spans received")
 	}
+	// Shutdown flushes the meter provider as well.
+	metrics.mu.Lock()
+	received = len(metrics.requests)
+	metrics.mu.Unlock()
+	if received == 0 {
+		t.Errorf("no metrics received")
+	}
+	// Loggers created before Init export through the installed provider and
+	// carry the trace of the context they were called with.
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+	var traceIDs [][]byte
+	for _, req := range logs.requests {
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				for _, lr := range sl.LogRecords {
+					traceIDs = append(traceIDs, lr.TraceId)
+				}
+			}
+		}
+	}
+	wantTraceID := span.SpanContext().TraceID()
+	if len(traceIDs) != 1 || !bytes.Equal(traceIDs[0], wantTraceID[:]) {
+		t.Errorf("exported log trace IDs = %x, want [%s]", traceIDs, wantTraceID)
+	}
 }